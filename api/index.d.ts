@@ -39,6 +39,26 @@ export class Application {
    */
   constructor();
 
+  /**
+   * Object whose properties are local variables within the application.
+   * Once set, the value of `app.locals` properties persist throughout the life of the application.
+   */
+  locals: Record<string, any>;
+
+  /**
+   * The certificate of the certificate authority which issued the self-signed certificate of the server in PEM format
+   * (see the `selfSigned` TLS option), or null if self-signed certificates are not used.
+   * Clients should trust this certificate.
+   */
+  readonly ca: string | null;
+
+  /**
+   * The addresses of all listeners in the order of `listen` calls.
+   * An application can listen on several addresses, `app.shutdown()` stops all of them.
+   * The addresses are kept after `app.shutdown()`, until the next `listen` call.
+   */
+  readonly addresses: Address[];
+
   /**
    * Routes HTTP GET requests to the specified path with the specified middleware functions.
    *
@@ -49,6 +69,49 @@ export class Application {
    */
   get(path: string, ...middleware: Middleware[]): void;
 
+  /**
+   * Returns the value of the application setting.
+   *
+   * @param name The name of the setting
+   * @returns The value of the setting
+   */
+  get(name: string): any;
+
+  /**
+   * Assigns setting name to value.
+   *
+   * Settings used by the view engine subsystem:
+   *
+   * - `views`: the directory of the templates in the filesystem of the application (default `views`)
+   * - `view engine`: the default extension to use when omitted (default `html`)
+   * - `view cache`: enables caching of compiled templates (default `true`)
+   *
+   * @param name The name of the setting
+   * @param value The value of the setting
+   * @returns The application
+   */
+  set(name: string, value: any): Application;
+
+  /**
+   * Registers the given template engine function for the file extension.
+   *
+   * Templates with `.html` extension are rendered by the built-in engine based on Go's `html/template` package by default.
+   *
+   * Since scripts have no access to the filesystem, the engine function gets the template source instead of the path.
+   * The path is available as `filename` property of options.
+   * The engine should call the callback synchronously, or return the rendered string.
+   *
+   * @example
+   * app.engine("txt", (source, options, callback) => {
+   *   callback(null, source.replace(/{{(\w+)}}/g, (_, key) => options[key]))
+   * })
+   *
+   * @param ext The file extension
+   * @param fn The template engine function
+   * @returns The application
+   */
+  engine(ext: string, fn: (source: string, options: Record<string, any>, callback: (err: any, html?: string) => void) => string | void): Application;
+
   /**
    * Routes HTTP HEAD requests to the specified path with the specified middleware functions.
    *
@@ -125,6 +188,25 @@ export class Application {
    */
   static(path: string, docroot: string): void;
 
+  /**
+   * Routes WebSocket upgrade requests to the specified path.
+   *
+   * Upgrade requests are matched like GET requests and run through the application middlewares (`use`) and the given middleware functions first.
+   * The connection is upgraded only if all of them called `next` without sending a response.
+   * Events of the socket are delivered through the configured runner (the event loop, if any).
+   * The same path may have a `get` handler as well, it receives the requests which are not upgrade requests.
+   * Connections are accepted from any origin.
+   *
+   * @example
+   * app.ws("/echo", (socket, req) => {
+   *   socket.on("message", (data, binary) => socket.send(data))
+   * })
+   *
+   * @param path The path for which the handler is invoked (string or path pattern)
+   * @param middlewareAndHandler Middleware functions followed by the WebSocket connection handler
+   */
+  ws(path: string, ...middlewareAndHandler: [...Middleware[], WebSocketHandler]): void;
+
   /**
    * Starts the server.
    *
@@ -133,6 +215,384 @@ export class Application {
    * @returns The instance for fluent/chaining API
    */
   listen(addr?: string, callback?: () => void): void;
+
+  /**
+   * Starts the server with the given options.
+   *
+   * With the `tls` option the server accepts HTTPS connections.
+   * Calling `listen` again starts an additional server on another address (see `app.addresses`).
+   *
+   * @example
+   * app.listen({ port: 8443, tls: { cert: "certs/server.crt", key: "certs/server.key" } }, () => {
+   *   console.log("https://" + app.host)
+   * })
+   *
+   * @param options listen options
+   * @param callback called when the server is started
+   */
+  listen(options: ListenOptions, callback?: () => void): void;
+
+  /**
+   * Stops all servers of the application gracefully: stops accepting new connections and waits for in-flight requests.
+   * Active connections are closed after the timeout. Calling it again (or before `listen`) is harmless.
+   * When called by a route handler (like a `/shutdown` endpoint) or another handler, the response of the handler is still sent,
+   * and the promise is settled (the callback is called) after the handler returned, through the event loop.
+   * Without event loop it is settled when the JavaScript thread is next used, for example by a request to another application.
+   *
+   * @example
+   * app.shutdown("5s").then(() => console.log("stopped"))
+   *
+   * @param timeout drain timeout (zero closes active connections immediately), default is the `shutdownTimeout` listen option
+   * @param callback called with the error (if any) when the servers are stopped
+   * @returns Promise resolved when the servers are stopped, or rejected with the first serving or shutdown error
+   */
+  shutdown(timeout?: Duration, callback?: (err?: Error) => void): Promise<void>;
+
+  /**
+   * Stops all servers of the application gracefully with the default timeout.
+   *
+   * @param callback called with the error (if any) when the servers are stopped
+   * @returns Promise resolved when the servers are stopped, or rejected with the first serving or shutdown error
+   */
+  shutdown(callback: (err?: Error) => void): Promise<void>;
+
+  /**
+   * Registers an event handler. Handlers are called on the JavaScript thread, errors thrown by them are logged.
+   * The `listening` event is emitted synchronously by `listen`. The other events are emitted by the servers,
+   * they are delivered in order through the event loop, so handlers never block the servers. Without event loop
+   * the events are delivered when the JavaScript thread is next used by the application: before the next request
+   * (or WebSocket message) is handled, or when `listen` or `shutdown` is called.
+   *
+   * @example
+   * app.on("response", (res) => console.log(res.method, res.url, res.status, res.duration))
+   *
+   * @param event the `listening` event is emitted with the address of the started listener
+   * @param listener event handler
+   * @returns the application for chaining
+   */
+  on(event: "listening", listener: (address: Address) => void): Application;
+
+  /**
+   * Registers a handler of the `request` event, emitted before a request is handled.
+   */
+  on(event: "request", listener: (req: RequestEvent) => void): Application;
+
+  /**
+   * Registers a handler of the `response` event, emitted after a request is handled.
+   */
+  on(event: "response", listener: (res: ResponseEvent) => void): Application;
+
+  /**
+   * Registers a handler of the `error` event, emitted when a server fails (for example accepting connections) or a request handler panics.
+   */
+  on(event: "error", listener: (err: Error) => void): Application;
+
+  /**
+   * Registers a handler of the `clientError` event, emitted on client errors like malformed requests or failed TLS handshakes.
+   */
+  on(event: "clientError", listener: (err: Error) => void): Application;
+
+  /**
+   * Registers a handler of the `connection` event, emitted when a new connection is accepted.
+   */
+  on(event: "connection", listener: (conn: ConnectionEvent) => void): Application;
+
+  /**
+   * Registers a handler of the `close` event, emitted when a server is stopped.
+   */
+  on(event: "close", listener: () => void): Application;
+}
+
+/**
+ * Payload of the `request` event.
+ */
+export interface RequestEvent {
+  /**
+   * HTTP method of the request.
+   */
+  method: string;
+
+  /**
+   * Path and query of the request.
+   */
+  url: string;
+
+  /**
+   * HTTP protocol version, like 1.1 or 2.0.
+   */
+  httpVersion: string;
+
+  /**
+   * Network address of the client.
+   */
+  remoteAddress: string;
+}
+
+/**
+ * Payload of the `response` event.
+ */
+export interface ResponseEvent {
+  /**
+   * HTTP method of the request.
+   */
+  method: string;
+
+  /**
+   * Path and query of the request.
+   */
+  url: string;
+
+  /**
+   * HTTP status code of the response.
+   */
+  status: number;
+
+  /**
+   * Duration of the request handling in milliseconds.
+   */
+  duration: number;
+}
+
+/**
+ * Payload of the `connection` event.
+ */
+export interface ConnectionEvent {
+  /**
+   * Network address of the client.
+   */
+  remoteAddress: string;
+
+  /**
+   * Network address of the listener.
+   */
+  localAddress: string;
+}
+
+/**
+ * Address of a listener.
+ */
+export interface Address {
+  /**
+   * Host name and port separated by colon, or the path of a unix domain socket.
+   */
+  host: string;
+
+  /**
+   * Host name or IP address, null for unix domain sockets.
+   */
+  hostname: string | null;
+
+  /**
+   * TCP port number, null for unix domain sockets.
+   */
+  port: number | null;
+
+  /**
+   * The protocol served on the address: either http or https.
+   */
+  protocol: string;
+}
+
+/**
+ * Options of `app.listen()`.
+ */
+export interface ListenOptions {
+  /**
+   * TCP port number, if 0 or missing then random unused port will be allocated.
+   */
+  port?: number;
+
+  /**
+   * Host name or IP address for listening on.
+   */
+  host?: string;
+
+  /**
+   * Unix domain socket path for listening on, instead of TCP port and host.
+   * The `app.host` property contains the path, `app.hostname` and `app.port` are null.
+   */
+  path?: string;
+
+  /**
+   * TLS options for HTTPS.
+   */
+  tls?: TLSOptions;
+
+  /**
+   * HTTP/2 options. HTTP/2 is negotiated over TLS by default, cleartext HTTP/2 requires the `h2c` option.
+   */
+  http2?: HTTP2Options;
+
+  /**
+   * Serves HTTP/3 over QUIC on the UDP port with the same number, alongside the TCP listener.
+   * Requires the `tls` option. HTTP/1.1 and HTTP/2 responses advertise the HTTP/3 endpoint in the `Alt-Svc` header.
+   * The timeouts, limits and server events apply to HTTP/3 connections as well. As HTTP/3 request headers are read
+   * before the request is handled, `readHeaderTimeout` limits the QUIC handshake instead.
+   */
+  http3?: boolean;
+
+  /**
+   * Maximum duration for reading the entire request, including the body. Default is no timeout.
+   * Durations are milliseconds or duration strings, like `"1.5s"` or `"2m"`.
+   */
+  readTimeout?: Duration;
+
+  /**
+   * The amount of time allowed to read request headers. Default is the `readTimeout`.
+   */
+  readHeaderTimeout?: Duration;
+
+  /**
+   * Maximum duration before timing out writes of the response. Default is no timeout.
+   */
+  writeTimeout?: Duration;
+
+  /**
+   * Maximum amount of time to wait for the next request on keep-alive connections. Default is the `readTimeout`.
+   */
+  idleTimeout?: Duration;
+
+  /**
+   * Maximum duration of waiting for active connections on shutdown. Default is 500 milliseconds.
+   * Zero means that active connections are closed immediately, without draining.
+   */
+  shutdownTimeout?: Duration;
+
+  /**
+   * Maximum number of bytes of request headers, including the request line. Default is 1 MB.
+   * Requests with larger headers are answered with 431 status code.
+   */
+  maxHeaderBytes?: number;
+}
+
+/**
+ * Duration in milliseconds, or duration string with unit suffix (ms, s, m, h), like `"1.5s"`.
+ */
+export type Duration = number | string;
+
+/**
+ * HTTP/2 server options. Missing or zero values mean the defaults of the HTTP/2 server.
+ */
+export interface HTTP2Options {
+  /**
+   * Accepts HTTP/2 over cleartext TCP connections (h2c), both with prior knowledge and with HTTP/1.1 upgrade.
+   */
+  h2c?: boolean;
+
+  /**
+   * The maximum number of concurrent streams per connection (default 250).
+   */
+  maxConcurrentStreams?: number;
+
+  /**
+   * The largest frame the server is willing to read, between 16KiB and 16MiB (default 1MiB).
+   */
+  maxReadFrameSize?: number;
+
+  /**
+   * The initial flow control window size of connections (default 1MiB).
+   */
+  maxUploadBufferPerConnection?: number;
+
+  /**
+   * The initial flow control window size of streams (default 1MiB).
+   */
+  maxUploadBufferPerStream?: number;
+}
+
+/**
+ * TLS options for listening on HTTPS.
+ *
+ * PEM values are either inline PEM strings or file paths, read from the filesystem of the application.
+ */
+export interface TLSOptions {
+  /**
+   * The certificate chain in PEM format.
+   */
+  cert?: string;
+
+  /**
+   * The private key in PEM format.
+   */
+  key?: string;
+
+  /**
+   * Trusted CA certificates in PEM format for verifying client certificates.
+   */
+  ca?: string | string[];
+
+  /**
+   * Generates a certificate at startup, issued by an ephemeral certificate authority (see `app.ca`).
+   * The certificate is valid for the given host names and IP addresses, or (if true) for localhost, 127.0.0.1, ::1 and the listening host.
+   */
+  selfSigned?: boolean | string[];
+
+  /**
+   * Requests client certificates for mutual TLS.
+   * With `required` the connection is rejected without a valid client certificate, with `optional` only invalid certificates are rejected.
+   * Client certificates are verified against the `ca` certificates, the verified certificate is available as `req.client`.
+   */
+  clientAuth?: "optional" | "required";
+}
+
+/**
+ * The verified client certificate of a mutual TLS connection.
+ */
+export interface ClientCertificate {
+  /**
+   * The distinguished name of the subject, for example `CN=partner,O=Example`.
+   */
+  subject: string;
+
+  /**
+   * The common name of the subject.
+   */
+  commonName: string;
+
+  /**
+   * The distinguished name of the issuer.
+   */
+  issuer: string;
+
+  /**
+   * The serial number in hexadecimal format.
+   */
+  serialNumber: string;
+
+  /**
+   * The SHA-256 fingerprint of the certificate as colon separated hexadecimal bytes.
+   */
+  fingerprint: string;
+
+  /**
+   * The start of the validity period.
+   */
+  validFrom: Date;
+
+  /**
+   * The end of the validity period.
+   */
+  validTo: Date;
+
+  /**
+   * DNS names of the subject alternative name extension.
+   */
+  dnsNames: string[];
+
+  /**
+   * Email addresses of the subject alternative name extension.
+   */
+  emailAddresses: string[];
+
+  /**
+   * IP addresses of the subject alternative name extension.
+   */
+  ipAddresses: string[];
+
+  /**
+   * URIs of the subject alternative name extension.
+   */
+  uris: string[];
 }
 
 /**
@@ -151,13 +611,57 @@ export interface Request {
    * Contains key-value pairs of data submitted in the request body.
    * By default, it is undefined, and is populated when the request
    * Content-Type is `application/json`.
+   * When the request Content-Type is `application/octet-stream`, it contains the raw body as ArrayBuffer.
+   * When the request Content-Type is `application/xml`, `text/xml` or ends with `+xml`, it contains the parsed document
+   * using the same object mapping as `res.xml()`. Attribute and text values are strings, namespace prefixes of element names are dropped.
+   * Documents declaring another encoding than UTF-8 (like ISO-8859-1) are transcoded, unsupported encodings are rejected with an error.
+   */
+  body: Record<string, any> | ArrayBuffer | undefined;
+
+  /**
+   * This property is an object that contains per-request values seeded by the embedding Go application
+   * (for example an authenticated user record set by a Go HTTP middleware).
+   * This object defaults to empty.
    */
-  body: Record<string, any> | undefined;
+  context: Record<string, any>;
 
   /**
    * This property is an object that contains cookies sent by the request.
+   * Cookie values are URI decoded, JSON cookies (prefixed with `j:`) are parsed.
+   * When cookie secret is specified, signed cookies are available only in `signedCookies`.
+   */
+  cookies: Record<string, any>;
+
+  /**
+   * This property is an object that contains signed cookies sent by the request, unsigned and ready for use.
+   * Signed cookies reside in a different object to show developer intent; otherwise, a malicious attack could be placed on `req.cookies` values (which are easy to spoof).
+   * A signed cookie with invalid signature has the value `false`.
+   *
+   * Signing secret(s) can be specified from Go using the `WithSecret` option.
+   * Without secret this object is empty.
+   */
+  signedCookies: Record<string, any>;
+
+  /**
+   * Contains the value of the `Last-Event-ID` request header field sent by reconnecting Server-Sent Events clients,
+   * or undefined if the header field is not present.
    */
-  cookies: Record<string, string>;
+  lastEventId: string | undefined;
+
+  /**
+   * Contains the verified client certificate of a mutual TLS connection (see the `clientAuth` TLS option),
+   * or null if the client did not send a certificate.
+   *
+   * @example
+   * app.use((req, res, next) => {
+   *   if (!req.client || req.client.commonName != "partner") {
+   *     res.sendStatus(403)
+   *     return
+   *   }
+   *   next()
+   * })
+   */
+  readonly client: ClientCertificate | null;
 
   /**
    * Contains a string corresponding to the HTTP method of the request: GET, POST, PUT, and so on.
@@ -181,6 +685,11 @@ export interface Request {
    */
   protocol: string;
 
+  /**
+   * The HTTP version of the request: `1.0`, `1.1`, `2.0` or `3.0`.
+   */
+  httpVersion: string;
+
   /**
    * This property is an object containing a property for each query string parameter in the route.
    *
@@ -213,6 +722,34 @@ export interface Request {
    * @returns the header field value.
    */
   header: (field: string) => string;
+
+  /**
+   * Parses the Range header field of the request.
+   *
+   * The returned array has a `type` property containing the range unit (usually `bytes`).
+   * Returns `undefined` when the request has no Range header field,
+   * -1 when the range is unsatisfiable and -2 when the header field is malformed.
+   *
+   * @param size the maximum size of the resource
+   * @param options when `combine` is true, overlapping and adjacent ranges are combined
+   * @returns the parsed ranges (inclusive) or error code
+   */
+  range: (size: number, options?: { combine?: boolean }) => ByteRanges | -1 | -2 | undefined;
+}
+
+/**
+ * BinaryData is an ArrayBuffer or a view (typed array or DataView) of an ArrayBuffer.
+ */
+export type BinaryData = ArrayBuffer | ArrayBufferView;
+
+/**
+ * ByteRanges holds the ranges parsed from the Range header field.
+ */
+export interface ByteRanges extends Array<{ start: number; end: number }> {
+  /**
+   * The range unit, usually `bytes`.
+   */
+  type: string;
 }
 
 /**
@@ -220,12 +757,26 @@ export interface Request {
  *
  * In this documentation and by convention, the object is always referred to as `res` (and the HTTP request is `req`) but its actual name is determined by the parameters to the callback function in which you’re working.
  *
+ * Like in Express.js, the response is kept open after the middleware chain returns, until it is ended
+ * by a method sending the whole body (like `send`, `json` or `redirect`), by `end`, or the client disconnects.
+ * So a handler may respond later, for example from a timer.
+ *
  * @example
  * app.get("/user/:id", function (req, res) {
  *   res.send("user " + req.params.id);
  * });
+ *
+ * app.get("/slow", (req, res) => {
+ *   setTimeout(() => res.json({ slow: true }), 1000)
+ * });
  */
 export interface Response {
+  /**
+   * Object that contains response local variables scoped to the request.
+   * Use this property to pass data between middlewares, for example the authenticated user.
+   */
+  locals: Record<string, any>;
+
   /**
    * Appends the specified value to the HTTP response header field. If the header is not already set, it creates the header with the specified value.
    *
@@ -237,9 +788,67 @@ export interface Response {
   /**
    * Sends a JSON response. This method sends a response (with the correct content-type) that is the parameter converted to a JSON string.
    *
+   * Iterable iterators (objects with `next` and `Symbol.iterator` or `Symbol.asyncIterator` methods, like generator objects)
+   * are streamed as JSON array, see `jsonArray`. Other objects are serialized, even if they have a `next` property.
+   *
    * @param body the object to send
    */
-  json: (body: Record<string, any>) => Response;
+  json: (body: Record<string, any> | Iterator<any>) => Response;
+
+  /**
+   * Streams the items of an iterable as JSON array with "application/json" Content-Type.
+   * The items are encoded one at a time, so large responses are never materialized in memory. Encoded items are buffered and
+   * flushed to the client in chunks: when the buffer is full, every 100 milliseconds and before awaiting a promise.
+   *
+   * Async iterables are supported as iterators whose `next` method returns promises; promise items are awaited too.
+   * The response is ended when the iterator is done, when it throws or the returned promise is rejected.
+   * The status is sent before the first item, so a failure truncates the response (the array is not closed) and it keeps the 200 status.
+   * Failures after the first awaited promise can not be caught by the route handler, they are logged by the application logger.
+   * If the client disconnects, the iteration stops and the `return` method of the iterator is called.
+   *
+   * @example
+   * function* users(count) {
+   *   for (let id = 0; id < count; id++) yield { id, name: "user" + id };
+   * }
+   *
+   * app.get("/users", (req, res) => res.jsonArray(users(1000000)));
+   *
+   * @param items the items to send
+   */
+  jsonArray: (items: StreamItems) => Response;
+
+  /**
+   * Streams the items of an iterable as newline delimited JSON (one JSON encoded item per line) with "application/x-ndjson" Content-Type.
+   * Items are handled the same way as by `jsonArray`.
+   *
+   * @param items the items to send
+   */
+  ndjson: (items: StreamItems) => Response;
+
+  /**
+   * Sends a JSON response with JSONP support.
+   *
+   * The name of the callback query parameter is `callback` by default, it can be changed with the `jsonp callback name` application setting.
+   * Without callback parameter in the request it is identical to `json`.
+   *
+   * @example
+   * // GET /users?callback=handle
+   * res.jsonp({ user: "joe" })
+   * // => typeof handle === 'function' && handle({"user":"joe"}); (prefixed with an empty comment)
+   *
+   * @param body the object to send
+   */
+  jsonp: (body: Record<string, any>) => Response;
+
+  /**
+   * Sets the response HTTP status code and sends its string representation as the response body.
+   *
+   * @example
+   * res.sendStatus(403) // equivalent to res.status(403).text("Forbidden")
+   *
+   * @param code the HTTP status code
+   */
+  sendStatus: (code: number) => Response;
 
   /**
    * Sends a plain text response. This method sends a response (with the correct content-type) that is the string formatting result.
@@ -258,24 +867,53 @@ export interface Response {
   /**
    * Sends a binray response. This method sends a response (with the "application/octet-stream" content-type) that is the body paramter.
    *
+   * Requests with Range (and If-Range) header field are answered with partial content (206), using multipart/byteranges for multiple ranges.
+   *
+   * An ArrayBuffer, typed array or DataView is sent without copying, number arrays are converted element by element.
+   *
    * @param body the data to send
    */
-  binary: (body: string | number[] | ArrayBuffer) => Response;
+  binary: (body: string | number[] | BinaryData) => Response;
 
   /**
    * Sends the HTTP response.
    *
-   * When the parameter is a ArrayBuffer or number[], the method sets the Content-Type response header field to “application/octet-stream”.
+   * When the parameter is an ArrayBuffer, typed array or DataView, the method sets the Content-Type response header field to “application/octet-stream” and sends the data without copying.
    * When the parameter is a String, the method sets the Content-Type to “text/html”.
    * Otherwise the method sets the Content-Type to "application/json" and convert paramter to JSON representation before sending.
+   * If the Accept request header field prefers `application/xml` or `text/xml` over `application/json`, the parameter is sent as XML (see `xml`) instead.
+   * In this case the Vary response header field contains `Accept`.
+   *
+   * Requests with Range (and If-Range) header field are answered with partial content (206), using multipart/byteranges for multiple ranges.
    *
    * @param body the data to send
    */
-  send: (body: string | number[] | ArrayBuffer) => Response;
+  send: (body: string | number[] | BinaryData | Record<string, any>) => Response;
+
+  /**
+   * Sends an XML response with "application/xml" Content-Type. A string parameter is sent as is, other values are serialized to XML.
+   *
+   * Object to XML mapping:
+   *  - an object with a single (non-array) property is the root element, otherwise the properties are wrapped in a `root` element
+   *  - properties with `@` prefix are attributes, the `#text` property is the text content
+   *  - array values are repeated elements with the name of the property, items of a top-level array are `item` elements
+   *  - invalid element and attribute names (like names containing whitespace or quotes) are rejected with an error
+   *  - other values are text content, null and undefined are empty elements
+   *
+   * @example
+   * res.xml({ user: { "@id": 1, name: "Joe", roles: ["admin", "user"] } })
+   * // => <user id="1"><name>Joe</name><roles>admin</roles><roles>user</roles></user>
+   *
+   * @param body the XML string or the object to send
+   */
+  xml: (body: string | Record<string, any>) => Response;
 
   /**
    * Sets the HTTP status for the response.
    *
+   * The status is sent together with header fields on the first body write or when the response is ended,
+   * so header fields can be set after calling this method.
+   *
    * @param code the satus code value
    */
   status: (code: number) => Response;
@@ -288,11 +926,15 @@ export interface Response {
   type: (mime: string) => Response;
 
   /**
-   * Adds the header field to the Vary response header.
+   * Adds the header field to the Vary response header, if it is not there already.
+   * Field names are compared case-insensitively, `*` replaces any other fields.
+   *
+   * @example
+   * res.vary("User-Agent").vary(["Accept-Encoding", "Origin"])
    *
-   * @param header the header filed name
+   * @param header the header field name (or comma separated list of names) or array of header field names
    */
-  vary: (header: string) => Response;
+  vary: (header: string | string[]) => Response;
 
   /**
    * Sets the response’s HTTP header field to value.
@@ -302,11 +944,345 @@ export interface Response {
    */
   set: (field: string, value: string) => Response;
 
+  /**
+   * Sets cookie name to value. The value parameter may be a string or object converted to JSON.
+   *
+   * @param name the cookie name
+   * @param value the cookie value
+   * @param options the cookie options
+   */
+  cookie: (name: string, value: string | Record<string, any>, options?: CookieOptions) => Response;
+
+  /**
+   * Clears the cookie specified by name.
+   * Web browsers and other compliant clients will only clear the cookie if the given options is identical to those given to `cookie`, excluding `expires` and `maxAge`.
+   *
+   * @param name the cookie name
+   * @param options the cookie options
+   */
+  clearCookie: (name: string, options?: CookieOptions) => Response;
+
   /**
    * Redirects to the URL, with specified status, a positive integer that corresponds to an HTTP status code.
+   * If not specified, status defaults to 302 (Found).
+   *
+   * @example
+   * res.redirect("/foo/bar")
+   * res.redirect(301, "http://example.com")
    *
    * @param code the HTTP status code (301, 302, ...)
    * @param loc the location to redirect
    */
-  redirect: (code: number, loc: string) => Response;
+  redirect: ((loc: string) => Response) & ((code: number, loc: string) => Response);
+
+  /**
+   * Sets the response Location HTTP header field to the specified path.
+   * The value `back` refers to the URL specified in the Referer header field of the request or `/` if it is not specified.
+   * The same rules apply to `redirect`.
+   *
+   * @param path the location
+   */
+  location: (path: string) => Response;
+
+  /**
+   * Joins the links provided as properties of the parameter to populate the response's Link HTTP header field.
+   *
+   * @example
+   * res.links({ next: "/users?page=2", last: "/users?page=5" })
+   * // Link: </users?page=2>; rel="next", </users?page=5>; rel="last"
+   *
+   * @param links the links by relation type
+   */
+  links: (links: Record<string, string>) => Response;
+
+  /**
+   * Sends a chunk of the response body and flushes it to the client (using chunked transfer encoding).
+   * Call `end` to complete the response.
+   *
+   * @example
+   * app.get("/slow", (req, res) => {
+   *   res.write("Hello, ")
+   *   setTimeout(() => res.end("World!"), 1000)
+   * })
+   *
+   * @param chunk the data to send
+   */
+  write: (chunk: string | number[] | BinaryData) => Response;
+
+  /**
+   * Ends the response process, optionally sending a last chunk of data.
+   *
+   * Use to quickly end the response without any data (`res.status(404).end()`) or to complete a streaming response.
+   *
+   * @param chunk the data to send
+   */
+  end: (chunk?: string | number[] | BinaryData) => Response;
+
+  /**
+   * Sends the buffered status, header fields and body data to the client.
+   */
+  flush: () => Response;
+
+  /**
+   * Turns the response into a Server-Sent Events stream.
+   *
+   * Sets the Content-Type to `text/event-stream`, disables caching and sends the header fields immediately.
+   * The response is kept open until the stream is closed or the client disconnects.
+   *
+   * @example
+   * app.get("/events", (req, res) => {
+   *   const stream = res.sse({ heartbeat: 15000 })
+   *   const timer = setInterval(() => stream.send({ event: "tick", data: { now: Date.now() } }), 1000)
+   *   stream.on("close", () => clearInterval(timer))
+   * })
+   *
+   * @param options when `heartbeat` is set, a comment line is sent in every `heartbeat` milliseconds to keep the connection alive
+   * @returns the event stream
+   */
+  sse: (options?: { heartbeat?: number }) => EventStream;
+
+  /**
+   * Transfers the file at the given path from the filesystem of the application (see `WithFS` Go option).
+   *
+   * The Content-Type is set based on the file name extension. Range and conditional requests are supported.
+   * If a status other than 200 is set (for example `res.status(404).sendFile("/404.html")`), the whole file is sent with that status.
+   * Unless the `root` option is set, the path must be absolute. When `root` is set, paths containing `..` segments are rejected with 403 (Forbidden).
+   * Missing files are answered with 404 (Not Found).
+   *
+   * @example
+   * app.get("/files/:name", (req, res) => {
+   *   res.sendFile(req.params.name, { root: "/public" })
+   * })
+   *
+   * @param path the path of the file
+   * @param options `root` is the base directory for relative path, `headers` contains header fields to set,
+   * `maxAge` sets the max-age property of the Cache-Control header field in milliseconds (default 0)
+   */
+  sendFile: (path: string, options?: SendFileOptions) => Response;
+
+  /**
+   * Transfers the file at path as an attachment. Typically, browsers will prompt the user for download.
+   *
+   * The Content-Disposition header field filename parameter defaults to the base name of the path, which can be overridden with the `filename` parameter.
+   * A relative path is resolved against the root of the filesystem (or the `root` option). See `sendFile` for options.
+   *
+   * @param path the path of the file
+   * @param filename the file name presented to the user
+   * @param options the same options as for `sendFile`
+   */
+  download: ((path: string, filename?: string, options?: SendFileOptions) => Response) &
+    ((path: string, options: SendFileOptions) => Response);
+
+  /**
+   * Sets the Content-Disposition header field to attachment.
+   * If a filename is given, then it sets the Content-Type based on the extension and sets the filename parameter.
+   *
+   * @param filename the file name presented to the user
+   */
+  attachment: (filename?: string) => Response;
+
+  /**
+   * Renders a view and sends the rendered HTML string to the client.
+   *
+   * The view is looked up in the directory specified by the `views` application setting, the default extension is given by the `view engine` setting.
+   * Templates with `.html` extension are rendered by the built-in engine based on Go's `html/template` package:
+   * values are HTML-escaped and the other templates of the views directory can be included by their relative path (`{{template "partials/header.html" .}}`).
+   * Local variables of the view are the properties of `app.locals`, `res.locals` and `locals`.
+   *
+   * When a callback is given, it is called with an error or the rendered string instead of sending it.
+   *
+   * @example
+   * app.get("/login", (req, res) => {
+   *   res.render("login", { client: req.query.client_id })
+   * })
+   *
+   * @param view the name of the view
+   * @param locals local variables for the view
+   * @param callback called with the rendered string
+   */
+  render: ((view: string, locals?: Record<string, any>, callback?: (err: Error | null, html: string) => void) => Response) &
+    ((view: string, callback: (err: Error | null, html: string) => void) => Response);
+
+  /**
+   * Returns the HTTP response header specified by field. The match is case-insensitive.
+   *
+   * @param field the header field name
+   * @returns the header field value, array of values for multi-valued header field or undefined if not set
+   */
+  getHeader: (field: string) => string | string[] | undefined;
+
+  /**
+   * Removes the HTTP response header specified by field.
+   *
+   * @param field the header field name
+   */
+  removeHeader: (field: string) => Response;
+
+  /**
+   * Boolean property that indicates if the app sent HTTP headers for the response.
+   *
+   * Status code and header fields are buffered until the first body write or the end of the middleware chain.
+   */
+  readonly headersSent: boolean;
+}
+
+/**
+ * StreamItems are the items of a streaming JSON response: a (sync) iterable or an iterator,
+ * whose `next` method may return promises.
+ */
+export type StreamItems = Iterable<any> | Iterator<any> | AsyncIterator<any>;
+
+/**
+ * EventStream represents a Server-Sent Events stream created by `res.sse()`.
+ */
+export interface EventStream {
+  /**
+   * Sends an event to the client.
+   *
+   * The parameter is either an object with `event`, `data`, `id` and `retry` properties or the event data itself.
+   * Data other than string is serialized as JSON, multiline data is split into multiple `data` fields.
+   *
+   * @param event the event or the event data
+   */
+  send: (event: { event?: string; data?: any; id?: string; retry?: number } | any) => EventStream;
+
+  /**
+   * Sends a comment line to the client. Comments are ignored by clients, they can be used to keep the connection alive.
+   *
+   * @param text the comment text
+   */
+  comment: (text: string) => EventStream;
+
+  /**
+   * Closes the stream and ends the response.
+   */
+  close: () => EventStream;
+
+  /**
+   * Registers an event handler. The only supported event is `close`, emitted when the stream is closed or the client disconnects.
+   *
+   * @param event the event name
+   * @param handler the event handler
+   */
+  on: (event: "close", handler: () => void) => EventStream;
+}
+
+/**
+ * WebSocketHandler is called for every accepted WebSocket connection.
+ */
+export type WebSocketHandler = (socket: WebSocket, req: Request) => void;
+
+/**
+ * WebSocket represents an accepted WebSocket connection.
+ */
+export interface WebSocket {
+  /**
+   * Sends a message. String data is sent as text message, other data as binary message.
+   *
+   * @param data the message data
+   */
+  send: (data: string | number[] | BinaryData) => WebSocket;
+
+  /**
+   * Sends a ping control frame. The client answers with a pong frame.
+   *
+   * @param data the optional application data
+   */
+  ping: (data?: string | number[] | BinaryData) => WebSocket;
+
+  /**
+   * Sends an unsolicited pong control frame.
+   *
+   * @param data the optional application data
+   */
+  pong: (data?: string | number[] | BinaryData) => WebSocket;
+
+  /**
+   * Starts the closing handshake. The `close` event is emitted when the client answers.
+   *
+   * @param code the close code, defaults to 1000 (normal closure)
+   * @param reason the close reason
+   */
+  close: (code?: number, reason?: string) => WebSocket;
+
+  /**
+   * Registers an event handler.
+   *
+   * - `message`: a message is received, the handler gets the data (string for text, ArrayBuffer for binary messages) and a binary flag
+   * - `ping`, `pong`: a ping or pong control frame is received (pings are answered automatically), the handler gets the application data as ArrayBuffer
+   * - `close`: the connection is closed, the handler gets the close code and reason (code 1006 for abnormal closure)
+   * - `error`: a read error occurred, the handler gets the error
+   *
+   * @param event the event name
+   * @param handler the event handler
+   */
+  on: ((event: "message", handler: (data: string | ArrayBuffer, binary: boolean) => void) => WebSocket) &
+    ((event: "ping" | "pong", handler: (data: ArrayBuffer) => void) => WebSocket) &
+    ((event: "close", handler: (code: number, reason: string) => void) => WebSocket) &
+    ((event: "error", handler: (error: Error) => void) => WebSocket);
+}
+
+/**
+ * Options for sending files.
+ */
+export interface SendFileOptions {
+  /**
+   * Root directory for relative file path.
+   */
+  root?: string;
+
+  /**
+   * Header fields to serve with the file.
+   */
+  headers?: Record<string, string>;
+
+  /**
+   * Sets the max-age property of the Cache-Control header field in milliseconds.
+   */
+  maxAge?: number;
+}
+
+/**
+ * Options for setting cookies.
+ */
+export interface CookieOptions {
+  /**
+   * Domain name for the cookie. Defaults to the domain name of the app.
+   */
+  domain?: string;
+
+  /**
+   * Expiry date of the cookie in GMT. If not specified or set to 0, creates a session cookie.
+   */
+  expires?: Date;
+
+  /**
+   * Flags the cookie to be accessible only by the web server.
+   */
+  httpOnly?: boolean;
+
+  /**
+   * Convenient option for setting the expiry time relative to the current time in milliseconds.
+   */
+  maxAge?: number;
+
+  /**
+   * Path for the cookie. Defaults to “/”.
+   */
+  path?: string;
+
+  /**
+   * Marks the cookie to be used with HTTPS only.
+   */
+  secure?: boolean;
+
+  /**
+   * Indicates if the cookie should be signed. Requires secret specified from Go using the `WithSecret` option.
+   */
+  signed?: boolean;
+
+  /**
+   * Value of the “SameSite” Set-Cookie attribute. `true` means strict.
+   */
+  sameSite?: boolean | "lax" | "strict" | "none";
 }
@@ -5,14 +5,20 @@
 package muxpress
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	_ "embed"
+	"errors"
 	"net"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
 )
 
 var httpMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
@@ -30,41 +36,124 @@ func NewApplicationConstructor(runtime *goja.Runtime, option ...Option) (func(ca
 		this := call.This
 		app := newApplication(opts)
 
+		app.events.runtime = runtime
+		app.events.this = this
+
+		must(runtime, this.DefineDataPropertySymbol(applicationSymbol, runtime.ToValue(app), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE))
+
 		for _, method := range httpMethods {
 			mustSet(runtime, this, strings.ToLower(method), app.handlerFor(runtime, strings.ToUpper(method)))
 		}
 
 		mustSet(runtime, this, "static", app.static)
+		mustSet(runtime, this, "ws", app.ws)
+		mustSet(runtime, this, "set", app.set)
+		mustSet(runtime, this, "engine", app.engine)
 
 		mustSet(runtime, this, "use", app.use)
 		mustSet(runtime, this, "listen", app.listen)
 		mustSet(runtime, this, "shutdown", app.shutdown)
+		mustSet(runtime, this, "on", app.on)
 
 		mustSetGetter(runtime, this, "host", app.host)
 		mustSetGetter(runtime, this, "hostname", app.hostname)
 		mustSetGetter(runtime, this, "port", app.port)
+		mustSetGetter(runtime, this, "addresses", app.addresses)
+		mustSetGetter(runtime, this, "ca", app.ca)
+
+		locals := runtime.NewObject()
+
+		mustSet(runtime, this, "locals", locals)
+
+		app.views.locals = locals
 
 		return this
 	}, nil
 }
 
+// Serve accepts incoming connections on the listener and serves them in the background with the application.
+// The app parameter is an application object created by the application constructor (see [NewApplicationConstructor]).
+// It allows using listeners created by the embedding Go program, like [tls.NewListener] or inherited sockets.
+// Connections are plain HTTP unless [WithTLSConfig] option is used. Use the shutdown method of the application to stop serving.
+//
+// Like any access of the [goja.Runtime], Serve must be called on the goroutine running the JavaScript code
+// (for example from a Go function called by the script or through the event loop of [WithRunner]),
+// the application object and its list of servers are not synchronized.
+func Serve(app goja.Value, listener net.Listener) error {
+	obj, ok := app.(*goja.Object)
+	if !ok || obj == nil {
+		return errNotApplication
+	}
+
+	value := obj.GetSymbol(applicationSymbol)
+	if value == nil {
+		return errNotApplication
+	}
+
+	instance, ok := value.Export().(*application)
+	if !ok {
+		return errNotApplication
+	}
+
+	return instance.serve(listener)
+}
+
+// applicationSymbol is the key of the hidden property referring the application from the JavaScript object.
+var applicationSymbol = goja.NewSymbol("muxpress.application")
+
+var errNotApplication = errors.New("not an application object")
+
 type address struct {
 	host     string
 	hostname string
 	port     int
+	protocol string
 }
 
 type application struct {
 	*router
-	server  *server
-	address *address
+	handler  http.Handler
+	servers  []*server
+	addrs    []*address
+	settings map[string]interface{}
+	views    *views
+	events   *emitter
+
+	context func() context.Context
+	logger  logrus.FieldLogger
+
+	tlsConfig *tls.Config
+	authority *CertificateAuthority
+	limits    limits
+
+	thread *jsThread
 }
 
 func newApplication(opts *options) *application {
 	app := new(application)
 
-	app.router = newRouter(opts.runner, opts.filesystem)
-	app.server = newServer(opts.context, opts.logger)
+	app.router = newRouter(opts.thread.run, opts.filesystem)
+	app.router.secrets = opts.secrets
+	app.router.logger = opts.logger
+	app.context = opts.context
+	app.logger = opts.logger
+	app.tlsConfig = opts.tlsConfig
+	app.authority = opts.authority
+	app.limits = opts.limits
+	app.thread = opts.thread
+	app.settings = defaultSettings()
+	app.views = newViews(opts.filesystem, app.settings)
+	app.router.views = app.views
+	app.router.settings = app.settings
+
+	app.handler = app.router
+
+	for i := len(opts.httpMiddlewares) - 1; i >= 0; i-- {
+		app.handler = opts.httpMiddlewares[i](app.handler)
+	}
+
+	app.events = newEmitter(opts.thread, opts.logger)
+	app.handler = app.events.instrument(app.handler)
 
 	return app
 }
@@ -72,35 +161,73 @@ func newApplication(opts *options) *application {
 func (app *application) listen(call goja.FunctionCall, runtime *goja.Runtime) goja.Value { // nolint:ireturn
 	args := call.Arguments
 	idx := 0
-	addr := new(address)
+
+	var (
+		hostname string
+		port     int
+		path     string
+		tlsOpts  *tlsOptions
+		srvOpts  = &serverOptions{limits: app.limits, hooks: app.events.serverHooks()} //nolint:exhaustruct
+	)
+
+	if len(args) > idx && isListenOptions(args[idx]) {
+		obj := args[idx].ToObject(runtime)
+
+		if v := obj.Get("port"); isSet(v) {
+			port = int(v.ToInteger())
+		}
+
+		if v := obj.Get("host"); isSet(v) {
+			hostname = v.String()
+		}
+
+		if v := obj.Get("path"); isSet(v) {
+			path = v.String()
+		}
+
+		if v := obj.Get("tls"); isSet(v) {
+			tlsOpts = parseTLSOptions(runtime, v)
+		}
+
+		if v := obj.Get("http2"); isSet(v) {
+			srvOpts.http2, srvOpts.h2c = parseHTTP2Options(v)
+		}
+
+		srvOpts.http3 = isTrue(obj.Get("http3"))
+		srvOpts.limits = parseLimits(runtime, obj, app.limits)
+
+		idx++
+	}
 
 	if len(args) > idx && args[idx].ExportType().Kind() == reflect.Int64 {
-		addr.port = int(args[idx].ToInteger())
+		port = int(args[idx].ToInteger())
 		idx++
 	}
 
 	if len(args) > idx && args[idx].ExportType().Kind() == reflect.String {
-		addr.hostname = args[idx].String()
+		hostname = args[idx].String()
 		idx++
 	}
 
-	addr.host = net.JoinHostPort(addr.hostname, strconv.Itoa(addr.port))
-
-	tcp, err := app.server.listenAndServe(addr.host, app.router)
+	config, err := app.serverTLSConfig(tlsOpts, hostname)
 
 	must(runtime, err)
 
-	if addr.port == 0 {
-		addr.port = tcp.Port
-	}
+	srvOpts.tlsConfig = config
+
+	srv := newServer(app.context, app.logger)
+
+	var bound net.Addr
 
-	if len(addr.hostname) == 0 {
-		addr.hostname = defaultHost
+	if len(path) != 0 {
+		bound, err = srv.listenAndServeWith("unix", path, app.handler, srvOpts)
+	} else {
+		bound, err = srv.listenAndServeWith("tcp", net.JoinHostPort(hostname, strconv.Itoa(port)), app.handler, srvOpts)
 	}
 
-	addr.host = net.JoinHostPort(addr.hostname, strconv.Itoa(addr.port))
+	must(runtime, err)
 
-	app.address = addr
+	app.addServer(srv, newAddress(bound, hostname, config != nil))
 
 	if len(args) > idx {
 		if callback, ok := goja.AssertFunction(args[idx]); ok {
@@ -115,34 +242,262 @@ func (app *application) listen(call goja.FunctionCall, runtime *goja.Runtime) go
 	return nil
 }
 
+// serve serves connections accepted by the given listener, see [Serve].
+func (app *application) serve(listener net.Listener) error {
+	config, err := app.serverTLSConfig(nil, "")
+	if err != nil {
+		return err
+	}
+
+	srv := newServer(app.context, app.logger)
+
+	opts := &serverOptions{tlsConfig: config, limits: app.limits, hooks: app.events.serverHooks()} //nolint:exhaustruct
+
+	if err := srv.serveWith(listener, app.handler, opts); err != nil {
+		return err
+	}
+
+	app.addServer(srv, newAddress(listener.Addr(), "", config != nil))
+
+	return nil
+}
+
+// addServer registers a started server and its address and emits the listening event. An application can listen
+// on several addresses, the first one is the primary address (app.host, app.hostname and app.port).
+// The addresses of stopped servers are kept until the next listen after shutdown.
+func (app *application) addServer(srv *server, addr *address) {
+	if len(app.servers) == 0 {
+		app.addrs = nil
+	}
+
+	app.servers = append(app.servers, srv)
+	app.addrs = append(app.addrs, addr)
+
+	app.events.emit("listening", addr)
+}
+
+// serverTLSConfig returns the TLS configuration of the server, or nil for plain HTTP.
+// The hostname is added to the self-signed certificate.
+func (app *application) serverTLSConfig(tlsOpts *tlsOptions, hostname string) (*tls.Config, error) {
+	if tlsOpts == nil && app.tlsConfig == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	if tlsOpts == nil {
+		tlsOpts = new(tlsOptions)
+	}
+
+	if tlsOpts.selfSigned {
+		authority, err := app.certificateAuthority()
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := authority.issue(x509.ExtKeyUsageServerAuth, tlsOpts.selfSignedHosts(hostname)...)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsOpts.certificates = append(tlsOpts.certificates, cert)
+	}
+
+	return tlsOpts.config(app.tlsConfig, app.filesystem)
+}
+
+// newAddress returns the address of a listener bound to addr. The hostname is the requested host name,
+// if it is missing then the bound IP address (or localhost for unspecified addresses) is used.
+// Non-TCP addresses (like unix socket paths) have no hostname and port.
+func newAddress(bound net.Addr, hostname string, secure bool) *address {
+	protocol := "http"
+	if secure {
+		protocol = "https"
+	}
+
+	tcp, ok := bound.(*net.TCPAddr)
+	if !ok {
+		return &address{host: bound.String(), protocol: protocol} //nolint:exhaustruct
+	}
+
+	if len(hostname) == 0 {
+		hostname = defaultHost
+
+		if tcp.IP != nil && !tcp.IP.IsUnspecified() {
+			hostname = tcp.IP.String()
+		}
+	}
+
+	return &address{
+		host:     net.JoinHostPort(hostname, strconv.Itoa(tcp.Port)),
+		hostname: hostname,
+		port:     tcp.Port,
+		protocol: protocol,
+	}
+}
+
+// toObject returns the JavaScript representation of the address, hostname and port are null for non-TCP addresses.
+func (addr *address) toObject(runtime *goja.Runtime) *goja.Object {
+	obj := runtime.NewObject()
+
+	mustSet(runtime, obj, "host", addr.host)
+	mustSet(runtime, obj, "hostname", nil)
+	mustSet(runtime, obj, "port", nil)
+	mustSet(runtime, obj, "protocol", addr.protocol)
+
+	if len(addr.hostname) != 0 {
+		mustSet(runtime, obj, "hostname", addr.hostname)
+		mustSet(runtime, obj, "port", addr.port)
+	}
+
+	return obj
+}
+
+// certificateAuthority returns the certificate authority of the application, it is created on first use.
+func (app *application) certificateAuthority() (*CertificateAuthority, error) {
+	if app.authority == nil {
+		ca, err := NewCertificateAuthority()
+		if err != nil {
+			return nil, err
+		}
+
+		app.authority = ca
+	}
+
+	return app.authority, nil
+}
+
+// isListenOptions returns true for a listen options object (not a callback function).
+func isListenOptions(value goja.Value) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok || obj == nil {
+		return false
+	}
+
+	_, isFunc := goja.AssertFunction(obj)
+
+	return !isFunc
+}
+
 func (app *application) host(_ goja.FunctionCall, runtime *goja.Runtime) goja.Value {
-	if app.address == nil {
+	if len(app.addrs) == 0 {
 		return goja.Null()
 	}
 
-	return runtime.ToValue(app.address.host)
+	return runtime.ToValue(app.addrs[0].host)
+}
+
+// ca returns the certificate of the certificate authority in PEM format, or null if self-signed certificates are not used.
+func (app *application) ca(_ goja.FunctionCall, runtime *goja.Runtime) goja.Value {
+	if app.authority == nil {
+		return goja.Null()
+	}
+
+	return runtime.ToValue(string(app.authority.CertificatePEM()))
 }
 
 func (app *application) hostname(_ goja.FunctionCall, runtime *goja.Runtime) goja.Value {
-	if app.address == nil {
+	if len(app.addrs) == 0 || len(app.addrs[0].hostname) == 0 {
 		return goja.Null()
 	}
 
-	return runtime.ToValue(app.address.hostname)
+	return runtime.ToValue(app.addrs[0].hostname)
 }
 
 func (app *application) port(_ goja.FunctionCall, runtime *goja.Runtime) goja.Value {
-	if app.address == nil {
+	if len(app.addrs) == 0 || app.addrs[0].port == 0 {
 		return goja.Null()
 	}
 
-	return runtime.ToValue(app.address.port)
+	return runtime.ToValue(app.addrs[0].port)
 }
 
-func (app *application) shutdown(_ goja.FunctionCall, runtime *goja.Runtime) goja.Value { // nolint:ireturn
-	app.server.shutdown()
+// addresses returns the addresses of all listeners in the order of listen calls.
+func (app *application) addresses(_ goja.FunctionCall, runtime *goja.Runtime) goja.Value {
+	addrs := make([]interface{}, 0, len(app.addrs))
 
-	return goja.Undefined()
+	for _, addr := range app.addrs {
+		addrs = append(addrs, addr.toObject(runtime))
+	}
+
+	return runtime.NewArray(addrs...)
+}
+
+// shutdown stops all servers of the application gracefully, with optional drain timeout and callback.
+// The returned promise is resolved when the servers are stopped (in-flight requests are finished or the timeout is exceeded),
+// or rejected with the first serving or shutdown error. The callback is called with the error (or undefined) too.
+// Without event loop the servers are awaited synchronously, otherwise the promise is settled through the runner.
+// When called on the JavaScript thread by the runner (like a request handler) the servers are never awaited synchronously:
+// the in-flight request of the handler is finished only after the handler returns. The promise is settled by a task
+// posted to the JavaScript thread, without event loop it runs when the thread is next used by an application.
+func (app *application) shutdown(call goja.FunctionCall, runtime *goja.Runtime) goja.Value { // nolint:ireturn
+	timeout := time.Duration(-1)
+	idx := 0
+
+	if v := call.Argument(idx); isSet(v) {
+		if _, isFunc := goja.AssertFunction(v); !isFunc {
+			timeout = parseDuration(runtime, v)
+			idx++
+		}
+	}
+
+	callback, _ := goja.AssertFunction(call.Argument(idx))
+
+	servers := app.servers
+
+	app.servers = nil
+
+	for _, srv := range servers {
+		srv.stop(timeout)
+	}
+
+	wait := func() error {
+		var first error
+
+		for _, srv := range servers {
+			if err := srv.wait(); err != nil && first == nil {
+				first = err
+			}
+		}
+
+		return first
+	}
+
+	promise, resolve, reject := runtime.NewPromise()
+
+	settle := func(err error) error {
+		reason := goja.Undefined()
+
+		if err != nil {
+			reason = runtime.NewGoError(err)
+
+			reject(reason)
+		} else {
+			resolve(goja.Undefined())
+		}
+
+		if callback == nil {
+			return nil
+		}
+
+		_, err = callback(goja.Undefined(), reason)
+
+		return err
+	}
+
+	if app.thread.synchronous && !app.thread.active() {
+		err := wait()
+
+		app.thread.sync() // delivers the events of the stopped servers, like close
+
+		must(runtime, settle(err))
+	} else {
+		go func() {
+			err := wait()
+
+			app.thread.post(func() error { return settle(err) })
+		}()
+	}
+
+	return runtime.ToValue(promise)
 }
 
 func (app *application) handlerFor(runtime *goja.Runtime, method string) func(goja.FunctionCall) goja.Value {
@@ -150,6 +505,10 @@ func (app *application) handlerFor(runtime *goja.Runtime, method string) func(go
 		args := call.Arguments
 		idx := 0
 
+		if name, isString := call.Argument(idx).Export().(string); method == http.MethodGet && len(args) == 1 && isString {
+			return app.setting(name, runtime)
+		}
+
 		var path string
 
 		if len(args) > idx {
@@ -197,6 +556,80 @@ func (app *application) static(call goja.FunctionCall, runtime *goja.Runtime) go
 	return goja.Undefined()
 }
 
+func (app *application) ws(call goja.FunctionCall, runtime *goja.Runtime) goja.Value {
+	args := call.Arguments
+	idx := 0
+
+	if len(args) <= idx {
+		throwf(runtime, "missing path parameter")
+	}
+
+	path := call.Argument(idx).String()
+
+	idx++
+
+	if len(args) <= idx {
+		throwf(runtime, "missing handler parameter")
+	}
+
+	handler, ok := goja.AssertFunction(args[len(args)-1])
+	if !ok {
+		throwf(runtime, "handler parameter must be a function")
+	}
+
+	middlewares := []middleware{}
+
+	for _, arg := range args[idx : len(args)-1] {
+		var m middleware
+
+		must(runtime, runtime.ExportTo(arg, &m))
+
+		middlewares = append(middlewares, m)
+	}
+
+	app.handleWebSocket(runtime, path, handler, middlewares...)
+
+	return goja.Undefined()
+}
+
+// set assigns setting name to value. Returns the application to allow chaining.
+func (app *application) set(call goja.FunctionCall, runtime *goja.Runtime) goja.Value {
+	if len(call.Arguments) == 0 {
+		throwf(runtime, "missing name parameter")
+	}
+
+	app.settings[call.Argument(0).String()] = call.Argument(1).Export()
+
+	return call.This
+}
+
+func (app *application) setting(name string, runtime *goja.Runtime) goja.Value {
+	value, found := app.settings[name]
+	if !found {
+		return goja.Undefined()
+	}
+
+	return runtime.ToValue(value)
+}
+
+// engine registers the given template engine function for the extension.
+func (app *application) engine(call goja.FunctionCall, runtime *goja.Runtime) goja.Value {
+	ext := normalizeExt(call.Argument(0).String())
+
+	if len(ext) == 0 {
+		throwf(runtime, "missing ext parameter")
+	}
+
+	fn, ok := goja.AssertFunction(call.Argument(1))
+	if !ok {
+		throwf(runtime, "engine parameter must be a function")
+	}
+
+	app.views.engines[ext] = fn
+
+	return call.This
+}
+
 const defaultHost = "localhost"
 
 //go:embed api/index.d.ts
@@ -7,10 +7,13 @@ package muxpress
 import (
 	"context"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/stretchr/testify/assert"
@@ -109,6 +112,8 @@ func Test_application_listen_host(t *testing.T) {
 	assert.Equal(t, "127.0.0.1:"+strconv.Itoa(int(port)), app.host(call, runtime).String())
 	assert.Equal(t, "127.0.0.1", app.hostname(call, runtime).String())
 
+	call.Arguments = nil
+
 	app.shutdown(call, runtime)
 }
 
@@ -134,12 +139,143 @@ func Test_application_listen_port(t *testing.T) {
 	assert.NotPanics(t, func() { app.listen(call, runtime) })
 
 	// new app on same port should panic
-	call.Arguments[0] = value(app.address.port)
+	call.Arguments[0] = value(app.addrs[0].port)
 	app = newApplication(opts)
 
 	assert.Panics(t, func() { app.listen(call, runtime) })
 }
 
+func Test_application_listen_path(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	opts, err := getopts()
+
+	assert.NoError(t, err)
+
+	app := newApplication(opts)
+
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	options := runtime.NewObject()
+
+	assert.NoError(t, options.Set("path", path))
+
+	call := goja.FunctionCall{
+		This:      runtime.GlobalObject(),
+		Arguments: []goja.Value{options},
+	}
+
+	assert.NotPanics(t, func() { app.listen(call, runtime) })
+
+	assert.Equal(t, path, app.host(call, runtime).String())
+	assert.Equal(t, goja.Null(), app.hostname(call, runtime))
+	assert.Equal(t, goja.Null(), app.port(call, runtime))
+
+	client := &http.Client{Transport: &http.Transport{ //nolint:exhaustruct
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, "http://unix/", nil)
+
+	assert.NoError(t, err)
+
+	resp, err := client.Do(req)
+
+	assert.NoError(t, err)
+
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	call.Arguments = nil
+
+	app.shutdown(call, runtime)
+}
+
+func Test_newAddress(t *testing.T) {
+	t.Parallel()
+
+	addr := newAddress(&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "", false) //nolint:exhaustruct
+
+	assert.Equal(t, &address{host: "localhost:8080", hostname: "localhost", port: 8080, protocol: "http"}, addr)
+
+	addr = newAddress(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "", true) //nolint:exhaustruct
+
+	assert.Equal(t, &address{host: "127.0.0.1:8080", hostname: "127.0.0.1", port: 8080, protocol: "https"}, addr)
+
+	addr = newAddress(&net.TCPAddr{IP: net.IPv6loopback, Port: 8080}, "example.com", false) //nolint:exhaustruct
+
+	assert.Equal(t, &address{host: "example.com:8080", hostname: "example.com", port: 8080, protocol: "http"}, addr)
+
+	addr = newAddress(&net.UnixAddr{Name: "/tmp/app.sock", Net: "unix"}, "", false)
+
+	assert.Equal(t, &address{host: "/tmp/app.sock", protocol: "http"}, addr) //nolint:exhaustruct
+}
+
+func Test_application_listen_multiple(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	value, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("%s", req.protocol))
+	app.listen(0, "127.0.0.1")
+	app.listen({ host: "127.0.0.1", tls: { selfSigned: true } })
+	app.addresses
+	`)
+
+	assert.NoError(t, err)
+
+	var addrs []map[string]interface{}
+
+	assert.NoError(t, runtime.ExportTo(value, &addrs))
+	assert.Len(t, addrs, 2)
+	assert.Equal(t, "http", addrs[0]["protocol"])
+	assert.Equal(t, "https", addrs[1]["protocol"])
+	assert.NotEqual(t, addrs[0]["port"], addrs[1]["port"])
+
+	host, err := runtime.RunString("app.host")
+
+	assert.NoError(t, err)
+	assert.Equal(t, addrs[0]["host"], host.Export())
+
+	client := tlsClient(t, runtime.Get("app").ToObject(runtime).Get("ca").String())
+
+	assert.Equal(t, "http", tlsGet(t, client, "http://"+addrs[0]["host"].(string)+"/"))   //nolint:forcetypeassert
+	assert.Equal(t, "https", tlsGet(t, client, "https://"+addrs[1]["host"].(string)+"/")) //nolint:forcetypeassert
+
+	_, err = runtime.RunString("app.shutdown()")
+
+	assert.NoError(t, err)
+
+	value, err = runtime.RunString("app.addresses.length")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), value.Export(), "the last addresses are kept")
+
+	assert.Eventually(t, func() bool {
+		for _, addr := range addrs {
+			conn, err := net.Dial("tcp", addr["host"].(string)) //nolint:forcetypeassert
+			if err == nil {
+				conn.Close()
+
+				return false
+			}
+		}
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
 func Test_application_handlerFor(t *testing.T) {
 	t.Parallel()
 
@@ -168,7 +304,7 @@ func Test_application_handlerFor(t *testing.T) {
 
 	app.listen(call, runtime)
 
-	url := "http://" + app.address.host + "/echo?message=dummy"
+	url := "http://" + app.addrs[0].host + "/echo?message=dummy"
 	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
 
 	assert.NoError(t, err)
@@ -190,6 +326,46 @@ func Test_application_handlerFor(t *testing.T) {
 	app.shutdown(call, runtime)
 }
 
+func Test_application_httpMiddleware(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	value := runtime.ToValue
+
+	seed := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := ContextWithValues(r.Context(), map[string]interface{}{"message": "seeded"})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	opts, err := getopts(WithHTTPMiddleware(seed))
+
+	assert.NoError(t, err)
+
+	app := newApplication(opts)
+
+	handler := app.handlerFor(runtime, http.MethodGet)
+
+	var echo middleware = func(req *goja.Object, res *goja.Object, next goja.Callable) {
+		ctx, isObject := req.Get("context").(*goja.Object)
+
+		assert.True(t, isObject)
+
+		callMethod(t, res, "text", ctx.Get("message"))
+	}
+
+	handler(goja.FunctionCall{This: runtime.GlobalObject(), Arguments: []goja.Value{value("/echo"), value(echo)}})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/echo", nil)
+
+	app.handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "seeded", rec.Body.String())
+}
+
 func Test_application_handlerFor_panic(t *testing.T) {
 	t.Parallel()
 
@@ -330,6 +506,184 @@ func Test_NewApplicationConstructor(t *testing.T) {
 
 var (
 	methods    = []string{"get", "head", "post", "put", "patch", "delete", "options"}
-	properties = []string{"host", "hostname", "port"}
-	functions  = []string{"listen", "shutdown", "static", "use"}
+	properties = []string{"host", "hostname", "port", "addresses", "locals"}
+	functions  = []string{"listen", "shutdown", "on", "static", "use", "ws", "set", "engine"}
 )
+
+func Test_application_settings(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	opts, err := getopts()
+
+	assert.NoError(t, err)
+
+	app := newApplication(opts)
+	this := runtime.NewObject()
+	value := runtime.ToValue
+
+	ret := app.set(goja.FunctionCall{This: this, Arguments: []goja.Value{value("views"), value("/templates")}}, runtime)
+
+	assert.Equal(t, this, ret)
+	assert.Equal(t, "/templates", app.setting("views", runtime).String())
+	assert.True(t, app.setting("view cache", runtime).ToBoolean())
+	assert.True(t, goja.IsUndefined(app.setting("missing", runtime)))
+
+	get := app.handlerFor(runtime, http.MethodGet)
+
+	assert.Equal(t, "/templates", get(goja.FunctionCall{Arguments: []goja.Value{value("views")}}).String()) // nolint:exhaustruct
+
+	engine, err := runtime.RunString("() => ''")
+
+	assert.NoError(t, err)
+
+	app.engine(goja.FunctionCall{This: this, Arguments: []goja.Value{value("txt"), engine}}, runtime)
+
+	assert.Contains(t, app.views.engines, ".txt")
+	assert.Panics(t, func() {
+		app.engine(goja.FunctionCall{This: this, Arguments: []goja.Value{value("txt"), value("foo")}}, runtime)
+	})
+}
+
+func Test_application_shutdown(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	value, err := runtime.RunString(`
+	const app = new Application()
+	const results = []
+
+	results.push(app.shutdown() instanceof Promise)
+
+	app.listen(0, "127.0.0.1")
+	app.listen(0, "127.0.0.1")
+
+	const promise = app.shutdown(100, (err) => results.push(err))
+
+	results.push(promise instanceof Promise, app.addresses.length)
+
+	app.shutdown("1s", (err) => results.push(err))
+
+	app.listen(0, "127.0.0.1")
+
+	results.push(app.addresses.length)
+
+	app.shutdown()
+
+	results
+	`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{true, nil, true, int64(2), nil, int64(1)}, value.Export())
+
+	_, err = runtime.RunString(`app.shutdown("forever")`)
+
+	assert.Error(t, err)
+}
+
+func Test_application_shutdown_error(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	app, err := runtime.RunString(`const app = new Application(); app`)
+
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+
+	defer listener.Close()
+
+	assert.NoError(t, Serve(app, failingListener{listener}))
+
+	instance, ok := app.ToObject(runtime).GetSymbol(applicationSymbol).Export().(*application)
+
+	assert.True(t, ok)
+	assert.Error(t, instance.servers[0].wait())
+
+	value, err := runtime.RunString(`
+	let message
+	app.shutdown((err) => { message = err.message })
+	message
+	`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "accept failed", value.String())
+}
+
+func Test_application_shutdown_runner(t *testing.T) {
+	t.Parallel()
+
+	jobs := make(chan func() error, 1)
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime, WithRunner(func(fn func() error) { jobs <- fn }))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	value, err := runtime.RunString(`
+	const app = new Application()
+	app.listen(0, "127.0.0.1")
+	app.shutdown()
+	`)
+
+	assert.NoError(t, err)
+
+	promise, ok := value.Export().(*goja.Promise)
+
+	assert.True(t, ok)
+	assert.Equal(t, goja.PromiseStatePending, promise.State())
+
+	select {
+	case job := <-jobs:
+		assert.NoError(t, job())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "promise not settled")
+	}
+
+	assert.Equal(t, goja.PromiseStateFulfilled, promise.State())
+}
+
+func Test_application_shutdown_on_thread(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	app, err := runtime.RunString(`
+	let stopped = false
+	const app = new Application()
+	app.listen(0, "127.0.0.1", () => app.shutdown(() => { stopped = true }))
+	app
+	`)
+
+	assert.NoError(t, err)
+
+	instance, ok := app.ToObject(runtime).GetSymbol(applicationSymbol).Export().(*application)
+
+	assert.True(t, ok)
+
+	// the listen callback runs on the JavaScript thread, so the servers are not awaited, the callback is called later
+	assert.False(t, runtime.Get("stopped").ToBoolean())
+
+	assert.Eventually(t, func() bool {
+		instance.thread.sync()
+
+		return runtime.Get("stopped").ToBoolean()
+	}, 5*time.Second, 10*time.Millisecond)
+}
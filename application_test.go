@@ -7,7 +7,11 @@ package muxpress_test
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -99,6 +103,153 @@ func ExampleNewApplicationConstructor_withContext() {
 	// output:
 }
 
+// This example serves the application on a listener created by the Go program.
+// The listener could be an inherited socket (systemd socket activation) or a [tls.NewListener] as well.
+func ExampleServe() {
+	runtime := goja.New()
+
+	ctor, err := muxpress.NewApplicationConstructor(runtime)
+	if err != nil {
+		panic(err)
+	}
+
+	err = runtime.Set("WebApp", ctor)
+	if err != nil {
+		panic(err)
+	}
+
+	app, err := runtime.RunString(`
+	const app = new WebApp()
+
+	app.get("/", (req, res) => {
+			res.text("Hello World!")
+	})
+
+	app
+`)
+	if err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := muxpress.Serve(app, listener); err != nil {
+		panic(err)
+	}
+
+	host, err := runtime.RunString("app.host")
+	if err != nil {
+		panic(err)
+	}
+
+	message := req.MustGet("http://" + host.String())
+
+	fmt.Println(message)
+
+	// output:
+	// Hello World!
+}
+
+// pipeListener is an in-memory listener, connections are created by dial using net.Pipe.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})} //nolint:exhaustruct
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+func (l *pipeListener) dial(ctx context.Context, _, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := muxpress.NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	app, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("Hello from %s", app.host))
+	app
+	`)
+
+	assert.NoError(t, err)
+
+	listener := newPipeListener()
+
+	assert.NoError(t, muxpress.Serve(app, listener))
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	client := &http.Client{Transport: &http.Transport{DialContext: listener.dial}} //nolint:exhaustruct
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, "http://pipe/", nil)
+
+	assert.NoError(t, err)
+
+	resp, err := client.Do(req)
+
+	assert.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello from pipe", string(body))
+
+	port, err := runtime.RunString("app.port")
+
+	assert.NoError(t, err)
+	assert.Equal(t, goja.Null(), port)
+
+	assert.Error(t, muxpress.Serve(runtime.ToValue(42), listener))
+	assert.Error(t, muxpress.Serve(runtime.NewObject(), listener))
+}
+
 func TestDeclaration(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+)
+
+const (
+	caCommonName     = "muxpress CA"
+	certificateValid = 365 * 24 * time.Hour
+	serialNumberBits = 128
+)
+
+// CertificateAuthority is an in-memory certificate authority for issuing certificates of self-signed HTTPS listeners.
+// Clients of the application should trust the certificate of the authority (see [CertificateAuthority.CertificatePEM] and [CertificateAuthority.CertPool]).
+type CertificateAuthority struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// NewCertificateAuthority creates a new ephemeral certificate authority with a freshly generated key.
+func NewCertificateAuthority() (*CertificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: caCommonName}, //nolint:exhaustruct
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValid),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}) //nolint:exhaustruct
+
+	return &CertificateAuthority{cert: cert, certPEM: certPEM, key: key}, nil
+}
+
+// CertificatePEM returns the certificate of the authority in PEM format.
+func (ca *CertificateAuthority) CertificatePEM() []byte {
+	return ca.certPEM
+}
+
+// CertPool returns a new certificate pool containing the certificate of the authority.
+func (ca *CertificateAuthority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+
+	pool.AddCert(ca.cert)
+
+	return pool
+}
+
+// issue creates a certificate signed by the authority for the given host names and IP addresses.
+func (ca *CertificateAuthority) issue(usage x509.ExtKeyUsage, hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateValid),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	if len(hosts) != 0 {
+		template.Subject = pkix.Name{CommonName: hosts[0]} //nolint:exhaustruct
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil //nolint:exhaustruct
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CertificateAuthority(t *testing.T) {
+	t.Parallel()
+
+	ca, err := NewCertificateAuthority()
+
+	assert.NoError(t, err)
+
+	block, _ := pem.Decode(ca.CertificatePEM())
+
+	assert.NotNil(t, block)
+
+	caCert, err := x509.ParseCertificate(block.Bytes)
+
+	assert.NoError(t, err)
+	assert.True(t, caCert.IsCA)
+	assert.Equal(t, caCommonName, caCert.Subject.CommonName)
+
+	cert, err := ca.issue(x509.ExtKeyUsageServerAuth, "example.com", "127.0.0.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com", cert.Leaf.Subject.CommonName)
+
+	for _, host := range []string{"example.com", "127.0.0.1"} {
+		_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: ca.CertPool()}) //nolint:exhaustruct
+
+		assert.NoError(t, err, host)
+	}
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: "other.com", Roots: ca.CertPool()}) //nolint:exhaustruct
+
+	assert.Error(t, err)
+
+	other, err := NewCertificateAuthority()
+
+	assert.NoError(t, err)
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: other.CertPool()}) //nolint:exhaustruct
+
+	assert.Error(t, err)
+
+	cert, err = ca.issue(x509.ExtKeyUsageClientAuth)
+
+	assert.NoError(t, err)
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{ //nolint:exhaustruct
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	assert.NoError(t, err)
+}
+
+func Test_application_listen_selfSigned(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	value, err := runtime.RunString(`
+	const app = new Application()
+	const before = app.ca
+	app.get("/", (req, res) => res.text("Hello"))
+	app.listen({ host: "127.0.0.1", tls: { selfSigned: true } })
+	;[before, app.ca, app.host]
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	var result []interface{}
+
+	assert.NoError(t, runtime.ExportTo(value, &result))
+	assert.Nil(t, result[0])
+
+	caPEM, _ := result[1].(string)
+	host, _ := result[2].(string)
+
+	assert.Equal(t, "Hello", tlsGet(t, tlsClient(t, caPEM), "https://"+host+"/"))
+}
+
+func Test_application_certificateAuthority(t *testing.T) {
+	t.Parallel()
+
+	opts, err := getopts()
+
+	assert.NoError(t, err)
+
+	app := newApplication(opts)
+
+	assert.Nil(t, app.authority)
+
+	ca, err := app.certificateAuthority()
+
+	assert.NoError(t, err)
+	assert.NotNil(t, ca)
+
+	again, err := app.certificateAuthority()
+
+	assert.NoError(t, err)
+	assert.Same(t, ca, again)
+}
+
+func Test_WithCertificateAuthority(t *testing.T) {
+	t.Parallel()
+
+	ca, err := NewCertificateAuthority()
+
+	assert.NoError(t, err)
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime, WithCertificateAuthority(ca))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	value, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("Hello"))
+	app.listen({ tls: { selfSigned: ["localhost"] } })
+	;[app.ca, app.port]
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	var result []interface{}
+
+	assert.NoError(t, runtime.ExportTo(value, &result))
+	assert.Equal(t, string(ca.CertificatePEM()), result[0])
+
+	url := fmt.Sprintf("https://localhost:%v/", result[1])
+
+	assert.Equal(t, "Hello", tlsGet(t, tlsClient(t, string(ca.CertificatePEM())), url))
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"context"
+
+	"github.com/dop251/goja"
+)
+
+type contextKey struct{}
+
+// ContextWithValues returns a copy of parent context in which the given values are associated with the request.
+// Values already associated with parent are kept unless overwritten by values.
+// Middlewares can access these values via the `context` property of the request object.
+//
+// The typical usage is to seed per-request values (authenticated user record, tracing information, etc.)
+// from a Go HTTP middleware registered by [WithHTTPMiddleware].
+func ContextWithValues(parent context.Context, values map[string]interface{}) context.Context {
+	merged := make(map[string]interface{}, len(values))
+
+	for key, value := range valuesFromContext(parent) {
+		merged[key] = value
+	}
+
+	for key, value := range values {
+		merged[key] = value
+	}
+
+	return context.WithValue(parent, contextKey{}, merged)
+}
+
+func valuesFromContext(ctx context.Context) map[string]interface{} {
+	values, _ := ctx.Value(contextKey{}).(map[string]interface{})
+
+	return values
+}
+
+func wrapContext(runtime *goja.Runtime, ctx context.Context) *goja.Object {
+	out := runtime.NewObject()
+
+	for key, value := range valuesFromContext(ctx) {
+		mustSet(runtime, out, key, value)
+	}
+
+	return out
+}
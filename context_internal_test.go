@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ContextWithValues(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, valuesFromContext(context.TODO()))
+
+	ctx := ContextWithValues(context.TODO(), map[string]interface{}{"foo": "bar", "answer": 42})
+
+	assert.Equal(t, map[string]interface{}{"foo": "bar", "answer": 42}, valuesFromContext(ctx))
+
+	ctx = ContextWithValues(ctx, map[string]interface{}{"foo": "dummy"})
+
+	assert.Equal(t, map[string]interface{}{"foo": "dummy", "answer": 42}, valuesFromContext(ctx))
+}
+
+func Test_wrapContext(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	obj := wrapContext(runtime, context.TODO())
+
+	assert.NotNil(t, obj)
+	assert.Empty(t, obj.Keys())
+
+	ctx := ContextWithValues(context.TODO(), map[string]interface{}{"user": map[string]interface{}{"name": "joe"}})
+
+	obj = wrapContext(runtime, ctx)
+
+	user, isObject := obj.Get("user").(*goja.Object)
+
+	assert.True(t, isObject)
+	assert.Equal(t, "joe", user.Get("name").String())
+}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	signedCookiePrefix = "s:"
+	jsonCookiePrefix   = "j:"
+)
+
+// signCookie signs the value the same way as the cookie-signature package used by Express.js does.
+func signCookie(value string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	mac.Write([]byte(value)) //nolint:errcheck
+
+	return value + "." + base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsignCookie returns the original value of a signed value if the signature is valid for any of the secrets.
+func unsignCookie(signed string, secrets []string) (string, bool) {
+	idx := strings.LastIndex(signed, ".")
+	if idx < 0 {
+		return "", false
+	}
+
+	value := signed[:idx]
+
+	for _, secret := range secrets {
+		if hmac.Equal([]byte(signCookie(value, secret)), []byte(signed)) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// cookieValue converts JavaScript value to cookie value. Non-string values are serialized as JSON cookies.
+func cookieValue(runtime *goja.Runtime, value goja.Value) string {
+	if str, ok := value.Export().(string); ok {
+		return str
+	}
+
+	bin, err := json.Marshal(value.Export())
+
+	must(runtime, err)
+
+	return jsonCookiePrefix + string(bin)
+}
+
+// uriComponentReplacer restores the characters left unescaped by encodeURIComponent, but escaped by url.QueryEscape.
+var uriComponentReplacer = strings.NewReplacer("+", "%20", "%21", "!", "%27", "'", "%28", "(", "%29", ")", "%2A", "*")
+
+// encodeCookieValue encodes cookie value the same way as Express.js does (using encodeURIComponent),
+// so signed cookies are readable by Express.js peers.
+func encodeCookieValue(value string) string {
+	return uriComponentReplacer.Replace(url.QueryEscape(value))
+}
+
+// decodeCookieValue decodes URI component encoded cookie value. Invalid encoded values are returned as is.
+func decodeCookieValue(value string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+// parseCookieValue converts decoded cookie value to JavaScript value. JSON cookies are parsed to objects.
+func parseCookieValue(runtime *goja.Runtime, value string) goja.Value {
+	if strings.HasPrefix(value, jsonCookiePrefix) {
+		var out interface{}
+
+		if err := json.Unmarshal([]byte(value[len(jsonCookiePrefix):]), &out); err == nil {
+			return runtime.ToValue(out)
+		}
+	}
+
+	return runtime.ToValue(value)
+}
+
+type cookieOptions struct {
+	*http.Cookie
+	signed bool
+}
+
+func newCookieOptions(name string, value string, options goja.Value) *cookieOptions {
+	opts := &cookieOptions{Cookie: &http.Cookie{Name: name, Value: value, Path: "/"}} //nolint:exhaustruct
+
+	obj, ok := options.(*goja.Object)
+	if !ok {
+		return opts
+	}
+
+	if v := obj.Get("domain"); isSet(v) {
+		opts.Domain = v.String()
+	}
+
+	if v := obj.Get("path"); isSet(v) {
+		opts.Path = v.String()
+	}
+
+	if v := obj.Get("expires"); isSet(v) {
+		if expires, ok := v.Export().(time.Time); ok {
+			opts.Expires = expires
+		}
+	}
+
+	if v := obj.Get("maxAge"); isSet(v) {
+		maxAge := time.Duration(v.ToInteger()) * time.Millisecond
+
+		opts.Expires = time.Now().Add(maxAge)
+		opts.MaxAge = int(maxAge / time.Second)
+
+		if opts.MaxAge <= 0 {
+			opts.MaxAge = -1
+		}
+	}
+
+	opts.HttpOnly = isTrue(obj.Get("httpOnly"))
+	opts.Secure = isTrue(obj.Get("secure"))
+	opts.SameSite = parseSameSite(obj.Get("sameSite"))
+	opts.signed = isTrue(obj.Get("signed"))
+
+	return opts
+}
+
+func parseSameSite(value goja.Value) http.SameSite {
+	if !isSet(value) {
+		return http.SameSiteDefaultMode
+	}
+
+	if b, ok := value.Export().(bool); ok {
+		if b {
+			return http.SameSiteStrictMode
+		}
+
+		return http.SameSiteDefaultMode
+	}
+
+	switch strings.ToLower(value.String()) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
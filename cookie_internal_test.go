@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_signCookie(t *testing.T) {
+	t.Parallel()
+
+	// test vector from cookie-signature package
+	assert.Equal(t, "hello.DGDUkGlIkCzPz+C0B064FNgHdEjox7ch8tOBGslZ5QI", signCookie("hello", "tobiiscool"))
+}
+
+func Test_unsignCookie(t *testing.T) {
+	t.Parallel()
+
+	signed := signCookie("hello", "tobiiscool")
+
+	value, ok := unsignCookie(signed, []string{"tobiiscool"})
+
+	assert.True(t, ok)
+	assert.Equal(t, "hello", value)
+
+	value, ok = unsignCookie(signed, []string{"foo", "tobiiscool"})
+
+	assert.True(t, ok)
+	assert.Equal(t, "hello", value)
+
+	_, ok = unsignCookie(signed, []string{"luna"})
+
+	assert.False(t, ok)
+
+	_, ok = unsignCookie("hello", []string{"tobiiscool"})
+
+	assert.False(t, ok)
+
+	_, ok = unsignCookie(signed+"x", []string{"tobiiscool"})
+
+	assert.False(t, ok)
+}
+
+func Test_cookieValue(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	value := runtime.ToValue
+
+	assert.Equal(t, "foo", cookieValue(runtime, value("foo")))
+	assert.Equal(t, `j:{"foo":"bar"}`, cookieValue(runtime, value(map[string]interface{}{"foo": "bar"})))
+
+	obj, isObject := parseCookieValue(runtime, `j:{"foo":"bar"}`).(*goja.Object)
+
+	assert.True(t, isObject)
+	assert.Equal(t, "bar", obj.Get("foo").String())
+	assert.Equal(t, "j:foo", parseCookieValue(runtime, "j:foo").String())
+	assert.Equal(t, "foo", parseCookieValue(runtime, "foo").String())
+}
+
+func Test_encodeCookieValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "foo", encodeCookieValue("foo"))
+	assert.Equal(t, "foo%20bar%3B%2C%22", encodeCookieValue(`foo bar;,"`))
+	assert.Equal(t, `foo bar;,"`, decodeCookieValue("foo%20bar%3B%2C%22"))
+	assert.Equal(t, "100%", decodeCookieValue("100%"))
+	assert.Equal(t, "s:foo", decodeCookieValue("s%3Afoo"))
+	assert.Equal(t, "a%3Ab%2Bc%3Dd%26e%40f%24g%2Fh%3F", encodeCookieValue("a:b+c=d&e@f$g/h?"))
+	assert.Equal(t, "-_.!~*'()%C3%A1", encodeCookieValue("-_.!~*'()á"))
+}
+
+func Test_encodeCookieValue_express(t *testing.T) {
+	t.Parallel()
+
+	// res.cookie('name', 'hello', { signed: true }) of Express.js with secret tobiiscool
+	const expected = "s%3Ahello.DGDUkGlIkCzPz%2BC0B064FNgHdEjox7ch8tOBGslZ5QI"
+
+	assert.Equal(t, expected, encodeCookieValue(signedCookiePrefix+signCookie("hello", "tobiiscool")))
+
+	value, ok := unsignCookie(strings.TrimPrefix(decodeCookieValue(expected), signedCookiePrefix), []string{"tobiiscool"})
+
+	assert.True(t, ok)
+	assert.Equal(t, "hello", value)
+}
+
+func Test_newCookieOptions(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	opts := newCookieOptions("foo", "bar", goja.Undefined())
+
+	assert.Equal(t, "foo", opts.Name)
+	assert.Equal(t, "bar", opts.Value)
+	assert.Equal(t, "/", opts.Path)
+	assert.False(t, opts.signed)
+
+	obj := runtime.NewObject()
+	expires := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, obj.Set("domain", "example.com"))
+	assert.NoError(t, obj.Set("path", "/admin"))
+	assert.NoError(t, obj.Set("expires", expires))
+	assert.NoError(t, obj.Set("httpOnly", true))
+	assert.NoError(t, obj.Set("secure", true))
+	assert.NoError(t, obj.Set("sameSite", "lax"))
+	assert.NoError(t, obj.Set("signed", true))
+
+	opts = newCookieOptions("foo", "bar", obj)
+
+	assert.Equal(t, "example.com", opts.Domain)
+	assert.Equal(t, "/admin", opts.Path)
+	assert.Equal(t, expires, opts.Expires)
+	assert.True(t, opts.HttpOnly)
+	assert.True(t, opts.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, opts.SameSite)
+	assert.True(t, opts.signed)
+
+	obj = runtime.NewObject()
+
+	assert.NoError(t, obj.Set("maxAge", 60000))
+
+	opts = newCookieOptions("foo", "bar", obj)
+
+	assert.Equal(t, 60, opts.MaxAge)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), opts.Expires, time.Second)
+}
+
+func Test_parseSameSite(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	value := runtime.ToValue
+
+	assert.Equal(t, http.SameSiteDefaultMode, parseSameSite(goja.Undefined()))
+	assert.Equal(t, http.SameSiteStrictMode, parseSameSite(value(true)))
+	assert.Equal(t, http.SameSiteDefaultMode, parseSameSite(value(false)))
+	assert.Equal(t, http.SameSiteStrictMode, parseSameSite(value("Strict")))
+	assert.Equal(t, http.SameSiteLaxMode, parseSameSite(value("lax")))
+	assert.Equal(t, http.SameSiteNoneMode, parseSameSite(value("none")))
+	assert.Equal(t, http.SameSiteDefaultMode, parseSameSite(value("dummy")))
+}
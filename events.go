@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+)
+
+var appEvents = map[string]struct{}{
+	"listening":   {},
+	"request":     {},
+	"response":    {},
+	"error":       {},
+	"clientError": {},
+	"connection":  {},
+	"close":       {},
+}
+
+var errNotHijacker = errors.New("response writer does not support hijacking")
+
+// emitter calls the registered handlers of application events. Events of the JavaScript thread (like listening)
+// are emitted synchronously, events of the server goroutines are posted to the JavaScript thread, so the servers
+// are never blocked by the handlers. Errors thrown by handlers are logged, they are not propagated.
+type emitter struct {
+	runtime *goja.Runtime
+	thread  *jsThread
+	logger  logrus.FieldLogger
+	this    *goja.Object
+
+	handlers map[string][]goja.Callable
+	mu       sync.RWMutex
+}
+
+func newEmitter(thread *jsThread, logger logrus.FieldLogger) *emitter {
+	return &emitter{ //nolint:exhaustruct
+		thread:   thread,
+		logger:   logger,
+		handlers: make(map[string][]goja.Callable),
+	}
+}
+
+// on registers event handler. Supported events are listening, request, response, error, clientError, connection and close.
+func (app *application) on(call goja.FunctionCall, runtime *goja.Runtime) goja.Value {
+	name := call.Argument(0).String()
+
+	if _, ok := appEvents[name]; !ok {
+		throwf(runtime, "unsupported event: %s", name)
+	}
+
+	handler, ok := goja.AssertFunction(call.Argument(1))
+	if !ok {
+		throwf(runtime, "handler parameter must be a function")
+	}
+
+	app.events.mu.Lock()
+	defer app.events.mu.Unlock()
+
+	app.events.handlers[name] = append(app.events.handlers[name], handler)
+
+	return call.This
+}
+
+func (em *emitter) has(name string) bool {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	return len(em.handlers[name]) != 0
+}
+
+// emit calls event handlers synchronously, it must be called on the JavaScript thread (like listen).
+func (em *emitter) emit(name string, args ...interface{}) {
+	em.mu.RLock()
+	handlers := em.handlers[name]
+	em.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		return
+	}
+
+	values := make([]goja.Value, len(args))
+
+	for idx, arg := range args {
+		switch val := arg.(type) {
+		case error:
+			values[idx] = em.runtime.NewGoError(val)
+		case *address:
+			values[idx] = val.toObject(em.runtime)
+		default:
+			values[idx] = em.runtime.ToValue(val)
+		}
+	}
+
+	for _, handler := range handlers {
+		if _, err := handler(em.this, values...); err != nil {
+			em.logger.WithError(err).WithField("event", name).Error("event handler failed")
+		}
+	}
+}
+
+// dispatch posts the event to the JavaScript thread without blocking, it is called by server goroutines.
+// The events are delivered in the order of dispatch. Without event loop they are delivered at the beginning
+// of the next function run on the JavaScript thread, for example before the handler of the next request.
+func (em *emitter) dispatch(name string, args ...interface{}) {
+	if !em.has(name) {
+		return
+	}
+
+	em.thread.post(func() error {
+		em.emit(name, args...)
+
+		return nil
+	})
+}
+
+// serverHooks returns the hooks of a server which emit the connection, clientError, error and close events.
+func (em *emitter) serverHooks() *serverHooks {
+	return &serverHooks{
+		onConnection: func(local, remote net.Addr) {
+			em.dispatch("connection", map[string]interface{}{
+				"remoteAddress": remote.String(),
+				"localAddress":  local.String(),
+			})
+		},
+		onClientError: func(message string) {
+			em.dispatch("clientError", errors.New(message)) //nolint:goerr113
+		},
+		onError: func(err error) {
+			em.dispatch("error", err)
+		},
+		onClose: func() {
+			em.dispatch("close")
+		},
+	}
+}
+
+// instrument wraps the handler to emit request and response events. The response event contains the status code
+// and the duration of the request handling in milliseconds.
+func (em *emitter) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !em.has("request") && !em.has("response") {
+			next.ServeHTTP(writer, request)
+
+			return
+		}
+
+		start := time.Now()
+
+		em.dispatch("request", map[string]interface{}{
+			"method":        request.Method,
+			"url":           request.URL.RequestURI(),
+			"httpVersion":   request.Proto[strings.Index(request.Proto, "/")+1:],
+			"remoteAddress": request.RemoteAddr,
+		})
+
+		rec := &statusRecorder{ResponseWriter: writer} //nolint:exhaustruct
+
+		next.ServeHTTP(rec, request)
+
+		em.dispatch("response", map[string]interface{}{
+			"method":   request.Method,
+			"url":      request.URL.RequestURI(),
+			"status":   rec.statusCode(),
+			"duration": float64(time.Since(start)) / float64(time.Millisecond),
+		})
+	})
+}
+
+// statusRecorder records the status code of the response. Flushing and hijacking are delegated to the wrapped writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if rec.status == 0 && (code >= http.StatusOK || code == http.StatusSwitchingProtocols) {
+		rec.status = code
+	}
+
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	return rec.ResponseWriter.Write(data)
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errNotHijacker
+	}
+
+	if rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+
+	return hijacker.Hijack()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// statusCode returns the recorded status code, 200 if nothing was written.
+func (rec *statusRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+
+	return rec.status
+}
@@ -0,0 +1,479 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_statusRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()} //nolint:exhaustruct
+
+	assert.Equal(t, http.StatusOK, rec.statusCode())
+
+	rec.WriteHeader(http.StatusEarlyHints)
+	rec.WriteHeader(http.StatusNotFound)
+	rec.WriteHeader(http.StatusInternalServerError)
+
+	assert.Equal(t, http.StatusNotFound, rec.statusCode())
+
+	rec = &statusRecorder{ResponseWriter: httptest.NewRecorder()} //nolint:exhaustruct
+
+	_, err := rec.Write([]byte("hello"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.statusCode())
+
+	rec.Flush()
+
+	assert.True(t, rec.Unwrap().(*httptest.ResponseRecorder).Flushed) //nolint:forcetypeassert
+
+	_, _, err = rec.Hijack()
+
+	assert.ErrorIs(t, err, errNotHijacker)
+}
+
+func Test_emitter_emit(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	logger, hook := test.NewNullLogger()
+
+	em := newEmitter(newJSThread(syncRunner(), true, logger), logger)
+	em.runtime = runtime
+	em.this = runtime.NewObject()
+
+	em.emit("close") // no handlers
+
+	fn, err := runtime.RunString(`(function (...args) { this.args = args; throw new Error("boom") })`)
+
+	assert.NoError(t, err)
+
+	handler, ok := goja.AssertFunction(fn)
+
+	assert.True(t, ok)
+
+	em.handlers["error"] = []goja.Callable{handler}
+
+	assert.True(t, em.has("error"))
+	assert.False(t, em.has("close"))
+
+	em.emit("error", errNotHijacker, &address{host: "localhost:80", hostname: "localhost", port: 80, protocol: "http"}, 42)
+
+	args := em.this.Get("args").ToObject(runtime)
+
+	assert.Equal(t, errNotHijacker.Error(), args.Get("0").ToObject(runtime).Get("message").String())
+	assert.Equal(t, "localhost:80", args.Get("1").ToObject(runtime).Get("host").String())
+	assert.Equal(t, int64(42), args.Get("2").ToInteger())
+
+	assert.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, "error", hook.LastEntry().Data["event"])
+}
+
+func Test_emitter_dispatch(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+
+	runner := func(fn func() error) {
+		<-release
+
+		assert.NoError(t, fn())
+	}
+
+	runtime := goja.New()
+	em := newEmitter(newJSThread(runner, false, logrus.StandardLogger()), logrus.StandardLogger())
+	em.runtime = runtime
+	em.this = runtime.NewObject()
+
+	got := make(chan interface{}, 3)
+
+	em.handlers["request"] = []goja.Callable{func(_ goja.Value, args ...goja.Value) (goja.Value, error) {
+		got <- args[0].Export()
+
+		return goja.Undefined(), nil
+	}}
+
+	em.dispatch("close") // no handlers
+
+	for i := 0; i < 3; i++ {
+		em.dispatch("request", i) // the runner is blocked, dispatch is not
+	}
+
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case value := <-got:
+			assert.Equal(t, int64(i), value)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "missing event")
+		}
+	}
+}
+
+func Test_emitter_dispatch_synchronous(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	thread := newJSThread(syncRunner(), true, logrus.StandardLogger())
+	em := newEmitter(thread, logrus.StandardLogger())
+	em.runtime = runtime
+	em.this = runtime.NewObject()
+
+	var got []interface{}
+
+	em.handlers["request"] = []goja.Callable{func(_ goja.Value, args ...goja.Value) (goja.Value, error) {
+		assert.True(t, thread.active(), "events must be delivered on the JavaScript thread")
+
+		got = append(got, args[0].Export())
+
+		return goja.Undefined(), nil
+	}}
+
+	for i := 0; i < 3; i++ {
+		em.dispatch("request", i)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Empty(t, got, "without event loop events are delivered on the next run")
+
+	thread.run(func() error {
+		assert.Equal(t, []interface{}{int64(0), int64(1), int64(2)}, got, "queued events are delivered first")
+
+		return nil
+	})
+
+	em.dispatch("request", 3)
+	thread.sync()
+
+	assert.Equal(t, []interface{}{int64(0), int64(1), int64(2), int64(3)}, got)
+}
+
+func Test_application_on(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	_, err = runtime.RunString(`const app = new Application(); app.on("foo", () => {})`)
+
+	assert.ErrorContains(t, err, "unsupported event: foo")
+
+	_, err = runtime.RunString(`app.on("close")`)
+
+	assert.Error(t, err)
+
+	value, err := runtime.RunString(`app.on("close", () => {}) === app`)
+
+	assert.NoError(t, err)
+	assert.True(t, value.ToBoolean())
+}
+
+// eventLoop runs the jobs of the runner on the test goroutine (like an event loop) and records the events.
+type eventLoop struct {
+	jobs   chan func() error
+	events chan string
+	values chan map[string]interface{}
+}
+
+func newEventLoop() *eventLoop {
+	return &eventLoop{
+		jobs:   make(chan func() error, 100),
+		events: make(chan string, 100),
+		values: make(chan map[string]interface{}, 100),
+	}
+}
+
+func (loop *eventLoop) runner(fn func() error) {
+	loop.jobs <- fn
+}
+
+// record registers event handlers for the named events of app.
+func (loop *eventLoop) record(t *testing.T, runtime *goja.Runtime, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		name := name
+
+		assert.NoError(t, runtime.Set("on_"+name, func(call goja.FunctionCall) goja.Value {
+			value := map[string]interface{}{}
+
+			if obj, ok := call.Argument(0).(*goja.Object); ok {
+				for _, key := range obj.Keys() {
+					value[key] = obj.Get(key).Export()
+				}
+
+				if message := obj.Get("message"); message != nil {
+					value["message"] = message.Export()
+				}
+			}
+
+			loop.events <- name
+			loop.values <- value
+
+			return goja.Undefined()
+		}))
+
+		_, err := runtime.RunString(`app.on("` + name + `", on_` + name + `)`)
+
+		assert.NoError(t, err)
+	}
+}
+
+// next runs jobs until the next event.
+func (loop *eventLoop) next(t *testing.T) (string, map[string]interface{}) {
+	t.Helper()
+
+	for {
+		select {
+		case name := <-loop.events:
+			return name, <-loop.values
+		case job := <-loop.jobs:
+			assert.NoError(t, job())
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "missing event")
+
+			return "", nil
+		}
+	}
+}
+
+func Test_application_events(t *testing.T) {
+	t.Parallel()
+
+	loop := newEventLoop()
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime, WithRunner(loop.runner))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	_, err = runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.status(201).text("ok"))
+	`)
+
+	assert.NoError(t, err)
+
+	loop.record(t, runtime, "listening", "connection", "request", "response", "clientError", "close")
+
+	ca, err := runtime.RunString(`app.listen({ host: "127.0.0.1", tls: { selfSigned: true } }); app.ca`)
+
+	assert.NoError(t, err)
+
+	client := tlsClient(t, ca.String())
+
+	name, value := loop.next(t)
+
+	assert.Equal(t, "listening", name)
+	assert.Equal(t, "https", value["protocol"])
+
+	host := value["host"].(string) //nolint:forcetypeassert
+
+	go tlsGet(t, client, "https://"+host+"/?foo=bar")
+
+	name, value = loop.next(t)
+
+	assert.Equal(t, "connection", name)
+	assert.NotEmpty(t, value["remoteAddress"])
+	assert.Equal(t, host, value["localAddress"])
+
+	name, value = loop.next(t)
+
+	assert.Equal(t, "request", name)
+	assert.Equal(t, "GET", value["method"])
+	assert.Equal(t, "/?foo=bar", value["url"])
+
+	name, value = loop.next(t)
+
+	assert.Equal(t, "response", name)
+	assert.Equal(t, int64(201), value["status"])
+	assert.IsType(t, float64(0), value["duration"])
+
+	conn, err := net.Dial("tcp", host)
+
+	assert.NoError(t, err)
+
+	_, err = conn.Write([]byte("\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09"))
+
+	assert.NoError(t, err)
+
+	conn.Close()
+
+	name, _ = loop.next(t)
+
+	assert.Equal(t, "connection", name)
+
+	name, _ = loop.next(t)
+
+	assert.Equal(t, "clientError", name)
+
+	_, err = runtime.RunString(`app.shutdown()`)
+
+	assert.NoError(t, err)
+
+	name, _ = loop.next(t)
+
+	assert.Equal(t, "close", name)
+}
+
+func Test_application_events_error(t *testing.T) {
+	t.Parallel()
+
+	loop := newEventLoop()
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime, WithRunner(loop.runner))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	app, err := runtime.RunString(`const app = new Application(); app`)
+
+	assert.NoError(t, err)
+
+	loop.record(t, runtime, "listening", "error", "close")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+
+	defer listener.Close()
+
+	assert.NoError(t, Serve(app, failingListener{listener}))
+
+	name, _ := loop.next(t)
+
+	assert.Equal(t, "listening", name)
+
+	name, value := loop.next(t)
+
+	assert.Equal(t, "error", name)
+	assert.Equal(t, "accept failed", value["message"])
+
+	name, _ = loop.next(t)
+
+	assert.Equal(t, "close", name)
+}
+
+func Test_application_events_nested_listen(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	done := make(chan goja.Value, 1)
+
+	go func() {
+		value, err := runtime.RunString(`
+		const app = new Application()
+		const ports = []
+		app.on("listening", (addr) => ports.push(addr.port))
+		app.listen({ host: "127.0.0.1" }, () => app.listen({ host: "127.0.0.1" }))
+		app.shutdown()
+		ports.length
+		`)
+
+		assert.NoError(t, err)
+
+		done <- value
+	}()
+
+	select {
+	case value := <-done:
+		assert.Equal(t, int64(2), value.Export())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "listen blocked by the listening event")
+	}
+}
+
+func Test_application_events_shutdown_from_route(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	closed := make(chan struct{}, 1)
+
+	assert.NoError(t, runtime.Set("onClose", func() { closed <- struct{}{} }))
+
+	value, err := runtime.RunString(`
+	let closes = 0
+	const app = new Application()
+	app.on("close", () => { closes++; onClose() })
+	app.post("/shutdown", (req, res) => {
+		app.shutdown()
+		res.text("bye")
+	})
+	app.listen({ host: "127.0.0.1" })
+	const other = new Application()
+	other.get("/", (req, res) => res.text(String(closes)))
+	other.listen({ host: "127.0.0.1" })
+	;[app.host, other.host]
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("other.shutdown()") //nolint:errcheck
+
+	var hosts []string
+
+	assert.NoError(t, runtime.ExportTo(value, &hosts))
+
+	resp, err := http.Post("http://"+hosts[0]+"/shutdown", "text/plain", nil) //nolint:noctx
+
+	assert.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bye", string(body))
+
+	resp.Body.Close()
+
+	// Without event loop the close event is delivered when the JavaScript thread is next used, by the other application.
+	// The runner is not wedged: other applications of the constructor still serve requests.
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + hosts[1] + "/") //nolint:noctx
+		if err != nil {
+			return false
+		}
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+
+		return err == nil && string(body) == "1"
+	}, 5*time.Second, 10*time.Millisecond, "missing close event")
+
+	select {
+	case <-closed:
+	default:
+		assert.Fail(t, "close handler not called")
+	}
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// sniffLen is the number of bytes used for content type detection, as in net/http.
+const sniffLen = 512
+
+var (
+	errPathNotAbsolute = errors.New("path must be absolute or specify root")
+	errPathForbidden   = errors.New("forbidden path")
+	errPathInvalid     = errors.New("invalid path")
+)
+
+// sendFile sends the file at the given path from the configured filesystem.
+// Options: root (base directory for relative path), headers (header fields to set), maxAge (Cache-Control max-age in milliseconds).
+func (resp *response) sendFile(name string, options goja.Value) *goja.Object {
+	var (
+		root    string
+		headers *goja.Object
+		maxAge  int64
+	)
+
+	if obj, ok := options.(*goja.Object); ok {
+		if v := obj.Get("root"); isSet(v) {
+			root = v.String()
+		}
+
+		if v, ok := obj.Get("headers").(*goja.Object); ok {
+			headers = v
+		}
+
+		if v := obj.Get("maxAge"); isSet(v) {
+			maxAge = v.ToInteger()
+		}
+	}
+
+	if len(root) == 0 && !path.IsAbs(filepath.ToSlash(name)) {
+		throw(resp.runtime, errPathNotAbsolute)
+	}
+
+	fullpath, err := resolvePath(root, name)
+	if err != nil {
+		resp.sendStatus(statusForPathError(err))
+
+		return resp.this
+	}
+
+	resp.serveFile(fullpath, headers, maxAge)
+
+	return resp.this
+}
+
+// download transfers the file at path as an attachment, filename defaults to the base name of the path.
+// Unlike sendFile, a relative path is resolved against the root of the filesystem.
+func (resp *response) download(name string, filename goja.Value, options goja.Value) *goja.Object {
+	if obj, ok := filename.(*goja.Object); ok && !isSet(options) {
+		filename, options = goja.Undefined(), obj
+	}
+
+	attachment := path.Base(filepath.ToSlash(name))
+
+	if isSet(filename) {
+		attachment = filename.String()
+	}
+
+	if opts, ok := options.(*goja.Object); !ok || !isSet(opts.Get("root")) {
+		if !path.IsAbs(filepath.ToSlash(name)) {
+			name = "/" + name
+		}
+	}
+
+	resp.attachment(resp.runtime.ToValue(attachment))
+
+	return resp.sendFile(name, options)
+}
+
+// attachment sets the Content-Disposition header field to attachment.
+// If a filename is given, it sets the filename parameter and the Content-Type based on the extension.
+func (resp *response) attachment(filename goja.Value) *goja.Object {
+	if !isSet(filename) {
+		resp.Header().Set("Content-Disposition", "attachment")
+
+		return resp.this
+	}
+
+	name := path.Base(filepath.ToSlash(filename.String()))
+
+	resp.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+
+	if ctype := mime.TypeByExtension(path.Ext(name)); len(ctype) != 0 {
+		resp.Header().Set("Content-Type", ctype)
+	}
+
+	return resp.this
+}
+
+func (resp *response) serveFile(name string, headers *goja.Object, maxAge int64) {
+	if resp.filesystem == nil {
+		throwf(resp.runtime, "filesystem not available")
+	}
+
+	file, err := resp.filesystem.Open(name)
+	if err != nil {
+		resp.sendStatus(statusForPathError(err))
+
+		return
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	must(resp.runtime, err)
+
+	if info.IsDir() {
+		resp.sendStatus(http.StatusNotFound)
+
+		return
+	}
+
+	if len(resp.Header().Get("Cache-Control")) == 0 {
+		resp.Header().Set("Cache-Control", "public, max-age="+strconv.FormatInt(maxAge/int64(time.Second/time.Millisecond), 10))
+	}
+
+	if headers != nil {
+		for _, key := range headers.Keys() {
+			resp.Header().Set(key, headers.Get(key).String())
+		}
+	}
+
+	resp.serveContent(info.Name(), info.ModTime(), file)
+}
+
+// serveContent sends the content with range and conditional request support.
+// The Content-Type is detected from the name (or content) if not set.
+// When a status other than 200 is set (like a custom 404 page), the content is sent as is with that status.
+func (resp *response) serveContent(name string, modtime time.Time, content io.ReadSeeker) {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if resp.closed {
+		return
+	}
+
+	if resp.code != 0 && resp.code != http.StatusOK {
+		resp.copyContent(name, content)
+	} else {
+		http.ServeContent(resp, resp.request, name, modtime, content)
+	}
+
+	resp.complete()
+}
+
+// copyContent sends the whole content with the buffered status, without range and conditional request handling.
+func (resp *response) copyContent(name string, content io.ReadSeeker) {
+	if len(resp.Header().Get("Content-Type")) == 0 {
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+
+		if len(ctype) == 0 {
+			var buf [sniffLen]byte
+
+			n, _ := io.ReadFull(content, buf[:])
+			ctype = http.DetectContentType(buf[:n])
+
+			_, err := content.Seek(0, io.SeekStart)
+
+			must(resp.runtime, err)
+		}
+
+		resp.Header().Set("Content-Type", ctype)
+	}
+
+	size, err := content.Seek(0, io.SeekEnd)
+
+	must(resp.runtime, err)
+
+	_, err = content.Seek(0, io.SeekStart)
+
+	must(resp.runtime, err)
+
+	if bodyAllowed(resp.code) {
+		resp.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	if resp.request != nil && resp.request.Method == http.MethodHead {
+		resp.writeHeader()
+
+		return
+	}
+
+	io.Copy(resp, content) //nolint:errcheck
+}
+
+// resolvePath joins root and name. When root is specified, name must not contain ".." segments.
+func resolvePath(root string, name string) (string, error) {
+	if strings.ContainsRune(name, 0) {
+		return "", errPathInvalid
+	}
+
+	name = filepath.ToSlash(name)
+
+	if len(root) == 0 {
+		return path.Clean(name), nil
+	}
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", errPathForbidden
+		}
+	}
+
+	return path.Join(filepath.ToSlash(root), name), nil
+}
+
+func statusForPathError(err error) int {
+	switch {
+	case errors.Is(err, errPathForbidden), errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, errPathInvalid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusNotFound
+	}
+}
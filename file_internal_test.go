@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFileResponse(t *testing.T, req *http.Request) (*response, *goja.Object, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	filesystem := afero.NewMemMapFs()
+
+	assert.NoError(t, afero.WriteFile(filesystem, "/public/index.html", []byte("<html></html>"), 0o644))
+	assert.NoError(t, afero.WriteFile(filesystem, "/public/data.json", []byte(`{"foo":"bar"}`), 0o644))
+	assert.NoError(t, afero.WriteFile(filesystem, "/secret.txt", []byte("secret"), 0o644))
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, req)
+	res.filesystem = filesystem
+
+	return res, wrapResponse(runtime, res), rec
+}
+
+func Test_response_sendFile(t *testing.T) {
+	t.Parallel()
+
+	res, obj, rec := newFileResponse(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	value := res.runtime.ToValue
+
+	callMethod(t, obj, "sendFile", value("/public/index.html"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=0", rec.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, rec.Header().Get("Last-Modified"))
+	assert.Equal(t, "<html></html>", rec.Body.String())
+}
+
+func Test_response_sendFile_options(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=1-3")
+
+	res, obj, rec := newFileResponse(t, req)
+	value := res.runtime.ToValue
+
+	opts := map[string]interface{}{"root": "/public", "maxAge": 60000, "headers": map[string]interface{}{"X-Foo": "bar"}}
+
+	callMethod(t, obj, "sendFile", value("data.json"), value(opts))
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "bar", rec.Header().Get("X-Foo"))
+	assert.Equal(t, `"fo`, rec.Body.String())
+}
+
+func Test_response_sendFile_status(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=1-3")
+
+	res, obj, rec := newFileResponse(t, req)
+	value := res.runtime.ToValue
+
+	callMethod(t, obj, "status", value(http.StatusNotFound))
+	callMethod(t, obj, "sendFile", value("/public/index.html"))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "13", rec.Header().Get("Content-Length"))
+	assert.Empty(t, rec.Header().Get("Content-Range"))
+	assert.Equal(t, "<html></html>", rec.Body.String())
+
+	res, obj, rec = newFileResponse(t, httptest.NewRequest(http.MethodHead, "/", nil))
+	value = res.runtime.ToValue
+
+	callMethod(t, obj, "status", value(http.StatusGone))
+	callMethod(t, obj, "sendFile", value("/secret.txt"))
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "6", rec.Header().Get("Content-Length"))
+	assert.Empty(t, rec.Body.String())
+}
+
+func Test_response_sendFile_errors(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		path string
+		code int
+	}{
+		"traversal": {"../secret.txt", http.StatusForbidden},
+		"missing":   {"missing.txt", http.StatusNotFound},
+		"directory": {"/", http.StatusNotFound},
+		"null":      {"index.html\x00", http.StatusBadRequest},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res, obj, rec := newFileResponse(t, httptest.NewRequest(http.MethodGet, "/", nil))
+			value := res.runtime.ToValue
+
+			callMethod(t, obj, "sendFile", value(tc.path), value(map[string]interface{}{"root": "/public"}))
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, http.StatusText(tc.code), rec.Body.String())
+		})
+	}
+
+	res, _, _ := newFileResponse(t, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Panics(t, func() { res.sendFile("relative.txt", goja.Undefined()) })
+}
+
+func Test_response_download(t *testing.T) {
+	t.Parallel()
+
+	res, obj, rec := newFileResponse(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	value := res.runtime.ToValue
+
+	callMethod(t, obj, "download", value("public/data.json"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename=data.json`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"foo":"bar"}`, rec.Body.String())
+
+	res, obj, rec = newFileResponse(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	value = res.runtime.ToValue
+
+	callMethod(t, obj, "download", value("index.html"), value("report 2023.html"), value(map[string]interface{}{"root": "/public"}))
+
+	assert.Equal(t, `attachment; filename="report 2023.html"`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+}
+
+func Test_response_attachment(t *testing.T) {
+	t.Parallel()
+
+	res, obj, rec := newFileResponse(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	value := res.runtime.ToValue
+
+	callMethod(t, obj, "attachment")
+
+	assert.Equal(t, "attachment", rec.Header().Get("Content-Disposition"))
+
+	callMethod(t, obj, "attachment", value("path/to/logo.png"))
+
+	assert.Equal(t, "attachment; filename=logo.png", rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+}
+
+func Test_resolvePath(t *testing.T) {
+	t.Parallel()
+
+	resolved, err := resolvePath("/public", "css/../data.json")
+
+	assert.ErrorIs(t, err, errPathForbidden)
+	assert.Empty(t, resolved)
+
+	resolved, err = resolvePath("/public", "css/site.css")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/public/css/site.css", resolved)
+
+	resolved, err = resolvePath("", "/public/../secret.txt")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/secret.txt", resolved)
+
+	_, err = resolvePath("", "/foo\x00bar")
+
+	assert.ErrorIs(t, err, errPathInvalid)
+}
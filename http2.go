@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"strconv"
+
+	"github.com/dop251/goja"
+	"golang.org/x/net/http2"
+)
+
+// parseHTTP2Options returns the HTTP/2 server settings and the h2c (HTTP/2 cleartext) flag from the http2 option of listen.
+// Zero values mean the defaults of the HTTP/2 server.
+func parseHTTP2Options(value goja.Value) (*http2.Server, bool) {
+	h2s := new(http2.Server)
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return h2s, false
+	}
+
+	if v := obj.Get("maxConcurrentStreams"); isSet(v) {
+		h2s.MaxConcurrentStreams = uint32(v.ToInteger())
+	}
+
+	if v := obj.Get("maxReadFrameSize"); isSet(v) {
+		h2s.MaxReadFrameSize = uint32(v.ToInteger())
+	}
+
+	if v := obj.Get("maxUploadBufferPerConnection"); isSet(v) {
+		h2s.MaxUploadBufferPerConnection = int32(v.ToInteger())
+	}
+
+	if v := obj.Get("maxUploadBufferPerStream"); isSet(v) {
+		h2s.MaxUploadBufferPerStream = int32(v.ToInteger())
+	}
+
+	return h2s, isTrue(obj.Get("h2c"))
+}
+
+// httpVersion returns the HTTP version of the request, like 1.1 or 2.0.
+func (req *request) httpVersion() string {
+	return strconv.Itoa(req.ProtoMajor) + "." + strconv.Itoa(req.ProtoMinor)
+}
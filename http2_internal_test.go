@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func Test_parseHTTP2Options(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	value, err := runtime.RunString(`({
+		h2c: true,
+		maxConcurrentStreams: 10,
+		maxReadFrameSize: 32768,
+		maxUploadBufferPerConnection: 1048576,
+		maxUploadBufferPerStream: 65536,
+	})`)
+
+	assert.NoError(t, err)
+
+	h2s, h2c := parseHTTP2Options(value)
+
+	assert.True(t, h2c)
+	assert.Equal(t, uint32(10), h2s.MaxConcurrentStreams)
+	assert.Equal(t, uint32(32768), h2s.MaxReadFrameSize)
+	assert.Equal(t, int32(1048576), h2s.MaxUploadBufferPerConnection)
+	assert.Equal(t, int32(65536), h2s.MaxUploadBufferPerStream)
+
+	h2s, h2c = parseHTTP2Options(goja.Undefined())
+
+	assert.False(t, h2c)
+	assert.Equal(t, new(http2.Server), h2s)
+}
+
+func Test_request_httpVersion(t *testing.T) {
+	t.Parallel()
+
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	assert.Equal(t, "1.1", newRequest(goja.New(), from).httpVersion())
+
+	from.ProtoMajor, from.ProtoMinor = 2, 0
+
+	assert.Equal(t, "2.0", newRequest(goja.New(), from).httpVersion())
+}
+
+func newHTTP2TestApp(t *testing.T, options string) (*goja.Runtime, string) {
+	t.Helper()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	host, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("%s", req.httpVersion))
+	app.listen(` + options + `)
+	app.host
+	`)
+
+	assert.NoError(t, err)
+
+	return runtime, host.String()
+}
+
+func Test_application_listen_h2c(t *testing.T) {
+	t.Parallel()
+
+	runtime, host := newHTTP2TestApp(t, `{ host: "127.0.0.1", http2: { h2c: true, maxConcurrentStreams: 7 } }`)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	conn, err := net.Dial("tcp", host)
+
+	assert.NoError(t, err)
+
+	transport := &http2.Transport{AllowHTTP: true} //nolint:exhaustruct
+
+	client, err := transport.NewClientConn(conn)
+
+	assert.NoError(t, err)
+
+	defer client.Close()
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, "http://"+host+"/", nil)
+
+	assert.NoError(t, err)
+
+	resp, err := client.RoundTrip(req)
+
+	assert.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0", string(body))
+	assert.Equal(t, uint32(7), client.State().MaxConcurrentStreams)
+
+	assert.Equal(t, "1.1", tlsGet(t, http.DefaultClient, "http://"+host+"/"))
+}
+
+func Test_application_listen_http2_tls(t *testing.T) {
+	t.Parallel()
+
+	runtime, host := newHTTP2TestApp(t, `{ host: "127.0.0.1", tls: { selfSigned: true }, http2: { maxConcurrentStreams: 5 } }`)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	client := tlsClient(t, runtime.Get("app").ToObject(runtime).Get("ca").String())
+	client.Transport.(*http.Transport).ForceAttemptHTTP2 = true //nolint:forcetypeassert
+
+	assert.Equal(t, "2.0", tlsGet(t, client, "https://"+host+"/"))
+
+	client = tlsClient(t, runtime.Get("app").ToObject(runtime).Get("ca").String())
+	client.Transport.(*http.Transport).TLSClientConfig.NextProtos = []string{"http/1.1"}                     //nolint:forcetypeassert
+	client.Transport.(*http.Transport).TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{} //nolint:forcetypeassert
+
+	assert.Equal(t, "1.1", tlsGet(t, client, "https://"+host+"/"))
+}
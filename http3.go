@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// altSvcMaxAge is the lifetime of the Alt-Svc advertisement in seconds.
+const altSvcMaxAge = 30 * 24 * 60 * 60
+
+var (
+	errHTTP3WithoutTLS = errors.New("http3 requires tls")
+	errHTTP3WithoutTCP = errors.New("http3 requires tcp listener")
+)
+
+// http3Listener serves HTTP/3 requests over QUIC on a UDP socket.
+type http3Listener struct {
+	srv  *http3.Server
+	conn net.PacketConn
+}
+
+// listenHTTP3 prepares serving the handler over QUIC on the UDP port of addr (the address of the TCP listener).
+// The limits and hooks of the options are applied as for the TCP listener, serving is started by serve.
+func (s *server) listenHTTP3(addr net.Addr, handler http.Handler, opts *serverOptions) (*http3Listener, error) {
+	if opts.tlsConfig == nil {
+		return nil, errHTTP3WithoutTLS
+	}
+
+	conn, err := net.ListenPacket("udp", addr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http3.Server{ //nolint:exhaustruct
+		Handler:   deadlineHandler(handler, opts.limits),
+		TLSConfig: opts.tlsConfig.Clone(),
+	}
+
+	opts.limits.applyHTTP3(srv)
+
+	if hooks := opts.hooks; hooks != nil {
+		srv.Logger = slog.New(&http3LogHandler{errorLog: errorLogWriter{logger: s.logger, hooks: hooks}})
+		srv.ConnContext = func(ctx context.Context, conn *quic.Conn) context.Context {
+			hooks.onConnection(conn.LocalAddr(), conn.RemoteAddr())
+
+			return ctx
+		}
+	}
+
+	return &http3Listener{srv: srv, conn: conn}, nil
+}
+
+// serve serves HTTP/3 requests until the server is closed, http.ErrServerClosed is returned after closing.
+func (l *http3Listener) serve() error {
+	return l.srv.Serve(l.conn)
+}
+
+func (l *http3Listener) Shutdown(ctx context.Context) error {
+	return l.srv.Shutdown(ctx)
+}
+
+func (l *http3Listener) Close() error {
+	err := l.srv.Close()
+
+	l.conn.Close()
+
+	return err
+}
+
+// applyHTTP3 sets the limits of the HTTP/3 server. Like for HTTP/1, the read timeout is used if the idle or the read header
+// timeout is not set. As the request headers are read by the HTTP/3 server before calling the handler, the read header timeout
+// limits the QUIC handshake.
+func (l limits) applyHTTP3(srv *http3.Server) {
+	srv.MaxHeaderBytes = l.maxHeaderBytes
+
+	srv.IdleTimeout = l.idleTimeout
+	if srv.IdleTimeout == 0 {
+		srv.IdleTimeout = l.readTimeout
+	}
+
+	handshake := l.readHeaderTimeout
+	if handshake == 0 {
+		handshake = l.readTimeout
+	}
+
+	if handshake != 0 {
+		srv.QUICConfig = &quic.Config{HandshakeIdleTimeout: handshake} //nolint:exhaustruct
+	}
+}
+
+// deadlineHandler sets the read and write deadline of the requests from the read and write timeouts.
+func deadlineHandler(next http.Handler, l limits) http.Handler {
+	if l.readTimeout == 0 && l.writeTimeout == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctrl := http.NewResponseController(writer)
+		now := time.Now()
+
+		if l.readTimeout != 0 {
+			ctrl.SetReadDeadline(now.Add(l.readTimeout)) //nolint:errcheck
+		}
+
+		if l.writeTimeout != 0 {
+			ctrl.SetWriteDeadline(now.Add(l.writeTimeout)) //nolint:errcheck
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// http3LogHandler writes the failures logged by the HTTP/3 server to the error log, like the HTTP/1 server does.
+// Connections closed by the server, by the client or by idle timeout are not failures.
+type http3LogHandler struct {
+	errorLog errorLogWriter
+}
+
+func (h *http3LogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *http3LogHandler) Handle(_ context.Context, record slog.Record) error {
+	var cause interface{}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "error" || attr.Key == "arg" {
+			cause = attr.Value.Any()
+
+			return false
+		}
+
+		return true
+	})
+
+	if record.Level < slog.LevelError && !isHTTP3ConnFailure(record.Message, cause) {
+		return nil
+	}
+
+	message := record.Message
+	if !strings.HasPrefix(message, "http3: ") {
+		message = "http3: " + message
+	}
+
+	if cause != nil {
+		message += fmt.Sprintf(": %v", cause)
+	}
+
+	_, err := h.errorLog.Write([]byte(message))
+
+	return err
+}
+
+func (h *http3LogHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *http3LogHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func isHTTP3ConnFailure(message string, cause interface{}) bool {
+	err, ok := cause.(error)
+	if !ok || message != "handling connection failed" {
+		return false
+	}
+
+	var (
+		idle   *quic.IdleTimeoutError
+		closed *quic.ApplicationError
+	)
+
+	if errors.As(err, &closed) && closed.Remote {
+		return false // closed by the client
+	}
+
+	return !errors.Is(err, http.ErrServerClosed) && !errors.As(err, &idle)
+}
+
+// altSvcHandler advertises the HTTP/3 endpoint on the given UDP port to HTTP/1 and HTTP/2 clients.
+func altSvcHandler(next http.Handler, port int) http.Handler {
+	value := `h3=":` + strconv.Itoa(port) + `"; ma=` + strconv.Itoa(altSvcMaxAge)
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.ProtoMajor < 3 {
+			writer.Header().Add("Alt-Svc", value)
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
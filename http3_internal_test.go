@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func http3Client(t *testing.T, caPEM string) *http.Client {
+	t.Helper()
+
+	pool := x509.NewCertPool()
+
+	assert.True(t, pool.AppendCertsFromPEM([]byte(caPEM)))
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13}} //nolint:exhaustruct
+
+	t.Cleanup(func() { transport.Close() })
+
+	return &http.Client{Transport: transport} //nolint:exhaustruct
+}
+
+func Test_altSvcHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := altSvcHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), 8443)
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, `h3=":8443"; ma=2592000`, rec.Header().Get("Alt-Svc"))
+
+	rec = httptest.NewRecorder()
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+	from.ProtoMajor, from.ProtoMinor = 3, 0
+
+	handler.ServeHTTP(rec, from)
+
+	assert.Empty(t, rec.Header().Get("Alt-Svc"))
+}
+
+func Test_application_listen_http3_without_tls(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	_, err = runtime.RunString(`new Application().listen({ host: "127.0.0.1", http3: true })`)
+
+	assert.ErrorContains(t, err, errHTTP3WithoutTLS.Error())
+}
+
+func Test_application_listen_http3(t *testing.T) {
+	t.Parallel()
+
+	runtime, host := newHTTP2TestApp(t, `{ host: "127.0.0.1", tls: { selfSigned: true }, http3: true }`)
+
+	instance, ok := runtime.Get("app").ToObject(runtime).GetSymbol(applicationSymbol).Export().(*application)
+
+	assert.True(t, ok)
+
+	// The HTTP/3 client may get the whole body before the handler returns, so the runtime is used via runner.
+	defer instance.thread.run(func() error {
+		_, err := runtime.RunString("app.shutdown()")
+
+		return err
+	})
+
+	ca := runtime.Get("app").ToObject(runtime).Get("ca").String()
+
+	client := tlsClient(t, ca)
+
+	resp, err := client.Get("https://" + host + "/")
+
+	assert.NoError(t, err)
+
+	resp.Body.Close()
+
+	assert.Equal(t, `h3=":`+host[len("127.0.0.1:"):]+`"; ma=2592000`, resp.Header.Get("Alt-Svc"))
+
+	resp, err = http3Client(t, ca).Get("https://" + host + "/")
+
+	assert.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "3.0", string(body))
+	assert.Empty(t, resp.Header.Get("Alt-Svc"))
+}
+
+func newHTTP3Options(t *testing.T, hooks *serverHooks) (*serverOptions, string) {
+	t.Helper()
+
+	certPEM, keyPEM := newTestCertificate(t)
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+
+	assert.NoError(t, err)
+
+	opts := &serverOptions{ //nolint:exhaustruct
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, //nolint:exhaustruct
+		http3:     true,
+		hooks:     hooks,
+	}
+
+	return opts, certPEM
+}
+
+func Test_server_http3_hooks(t *testing.T) {
+	t.Parallel()
+
+	rec := new(recordingHooks)
+	opts, ca := newHTTP3Options(t, rec.hooks())
+	srv := newServer(context.TODO, logrus.StandardLogger())
+
+	addr, err := srv.listenAndServeWith("tcp", "127.0.0.1:0", newHelloHandler(t), opts)
+
+	assert.NoError(t, err)
+
+	resp, err := http3Client(t, ca).Get("https://" + addr.String() + "/")
+
+	assert.NoError(t, err)
+
+	resp.Body.Close()
+
+	srv.stop(-1)
+
+	assert.NoError(t, srv.wait())
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	assert.Len(t, rec.connections, 1)
+	assert.Equal(t, "udp", rec.connections[0].Network())
+	assert.Empty(t, rec.errors)
+}
+
+func Test_server_http3_shutdown_drain(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	opts, ca := newHTTP3Options(t, nil)
+	srv := newServer(context.TODO, logrus.StandardLogger())
+
+	addr, err := srv.listenAndServeWith("tcp", "127.0.0.1:0", newSlowHandler(t, started, 100*time.Millisecond), opts)
+
+	assert.NoError(t, err)
+
+	client := http3Client(t, ca)
+	bodyCh := make(chan string, 1)
+
+	go func() {
+		var body []byte
+
+		resp, err := client.Get("https://" + addr.String() + "/")
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+
+			resp.Body.Close()
+		}
+
+		bodyCh <- string(body)
+	}()
+
+	<-started
+
+	srv.stop(time.Second)
+
+	assert.NoError(t, srv.wait())
+	assert.Equal(t, "done", <-bodyCh, "in-flight request should be finished")
+}
+
+func Test_server_http3_serve_failed(t *testing.T) {
+	t.Parallel()
+
+	rec := new(recordingHooks)
+	opts, _ := newHTTP3Options(t, rec.hooks())
+	srv := newServer(context.TODO, logrus.StandardLogger())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+
+	h3, err := srv.listenHTTP3(listener.Addr(), newHelloHandler(t), opts)
+
+	assert.NoError(t, err)
+
+	h3.conn.Close()
+
+	httpSrv, err := srv.newHTTPServer(newHelloHandler(t), opts)
+
+	assert.NoError(t, err)
+
+	srv.hooks = opts.hooks
+	srv.doneCh = make(chan struct{})
+
+	go srv.serve(listener, httpSrv, h3)
+
+	assert.Error(t, srv.wait())
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	assert.Len(t, rec.errors, 1)
+}
+
+func Test_limits_applyHTTP3(t *testing.T) {
+	t.Parallel()
+
+	srv := new(http3.Server)
+
+	limits{readTimeout: time.Second, maxHeaderBytes: 512}.applyHTTP3(srv) //nolint:exhaustruct
+
+	assert.Equal(t, time.Second, srv.IdleTimeout)
+	assert.Equal(t, time.Second, srv.QUICConfig.HandshakeIdleTimeout)
+	assert.Equal(t, 512, srv.MaxHeaderBytes)
+
+	srv = new(http3.Server)
+
+	limits{readHeaderTimeout: time.Second, idleTimeout: time.Minute}.applyHTTP3(srv) //nolint:exhaustruct
+
+	assert.Equal(t, time.Minute, srv.IdleTimeout)
+	assert.Equal(t, time.Second, srv.QUICConfig.HandshakeIdleTimeout)
+
+	srv = new(http3.Server)
+
+	limits{}.applyHTTP3(srv) //nolint:exhaustruct
+
+	assert.Zero(t, srv.IdleTimeout)
+	assert.Nil(t, srv.QUICConfig)
+}
+
+func Test_deadlineHandler(t *testing.T) {
+	t.Parallel()
+
+	var called bool
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	// the recorder does not support deadlines, setting them must not fail the request
+	handler := deadlineHandler(next, limits{readTimeout: time.Second, writeTimeout: time.Second}) //nolint:exhaustruct
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.True(t, called)
+}
+
+func Test_http3LogHandler(t *testing.T) {
+	t.Parallel()
+
+	rec := new(recordingHooks)
+	logger := slog.New(&http3LogHandler{errorLog: errorLogWriter{logger: logrus.StandardLogger(), hooks: rec.hooks()}})
+
+	logger.Debug("handling connection failed", "error", errors.New("stream reset")) //nolint:goerr113
+	logger.Debug("handling connection failed", "error", http.ErrServerClosed)
+	logger.Debug("handling connection failed", "error", &quic.IdleTimeoutError{})
+	logger.Debug("handling connection failed", "error", &quic.ApplicationError{Remote: true}) //nolint:exhaustruct
+	logger.Debug("handling request", "method", http.MethodGet)
+	logger.Error("http3: panic serving", "arg", "boom")
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	assert.Equal(t, []string{"http3: handling connection failed: stream reset"}, rec.clientErrors)
+	assert.Len(t, rec.errors, 1)
+	assert.EqualError(t, rec.errors[0], "http3: panic serving: boom")
+}
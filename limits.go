@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const defaultShutdownTimeout = 500 * time.Millisecond
+
+var (
+	errInvalidDuration = errors.New("invalid duration, must be milliseconds or duration string")
+	errInvalidLimit    = errors.New("invalid limit, must be a non-negative number")
+)
+
+// limits contains the timeouts and size limits of the HTTP server. Zero values mean no timeout or the default limit,
+// except the shutdown timeout: nil means the default, zero means closing active connections without draining.
+type limits struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   *time.Duration
+	maxHeaderBytes    int
+}
+
+// parseLimits returns base overridden with the limits given in the listen options object.
+// Durations are either milliseconds or duration strings (like "1.5s").
+func parseLimits(runtime *goja.Runtime, obj *goja.Object, base limits) limits {
+	out := base
+
+	durations := map[string]*time.Duration{
+		"readTimeout":       &out.readTimeout,
+		"readHeaderTimeout": &out.readHeaderTimeout,
+		"writeTimeout":      &out.writeTimeout,
+		"idleTimeout":       &out.idleTimeout,
+	}
+
+	for name, field := range durations {
+		if v := obj.Get(name); isSet(v) {
+			*field = parseDuration(runtime, v)
+		}
+	}
+
+	if v := obj.Get("shutdownTimeout"); isSet(v) {
+		timeout := parseDuration(runtime, v)
+
+		out.shutdownTimeout = &timeout
+	}
+
+	if v := obj.Get("maxHeaderBytes"); isSet(v) {
+		if v.ToInteger() < 0 {
+			throw(runtime, errInvalidLimit)
+		}
+
+		out.maxHeaderBytes = int(v.ToInteger())
+	}
+
+	return out
+}
+
+func parseDuration(runtime *goja.Runtime, value goja.Value) time.Duration {
+	var duration time.Duration
+
+	switch v := value.Export().(type) {
+	case int64:
+		duration = time.Duration(v) * time.Millisecond
+	case float64:
+		duration = time.Duration(v * float64(time.Millisecond))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			throw(runtime, errInvalidDuration)
+		}
+
+		duration = parsed
+	default:
+		throw(runtime, errInvalidDuration)
+	}
+
+	if duration < 0 {
+		throw(runtime, errInvalidDuration)
+	}
+
+	return duration
+}
+
+// apply sets the timeouts and limits of the HTTP server.
+func (l limits) apply(srv *http.Server) {
+	srv.ReadTimeout = l.readTimeout
+	srv.ReadHeaderTimeout = l.readHeaderTimeout
+	srv.WriteTimeout = l.writeTimeout
+	srv.IdleTimeout = l.idleTimeout
+	srv.MaxHeaderBytes = l.maxHeaderBytes
+}
+
+// shutdown returns the timeout for graceful shutdown, the default is used if it is not set.
+func (l limits) shutdown() time.Duration {
+	if l.shutdownTimeout == nil {
+		return defaultShutdownTimeout
+	}
+
+	return *l.shutdownTimeout
+}
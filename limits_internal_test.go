@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseLimits(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	value, err := runtime.RunString(`({
+		readTimeout: 1500,
+		readHeaderTimeout: "200ms",
+		writeTimeout: 2.5,
+		maxHeaderBytes: 4096,
+		shutdownTimeout: "2s",
+	})`)
+
+	assert.NoError(t, err)
+
+	base := limits{idleTimeout: time.Minute, readTimeout: time.Hour} //nolint:exhaustruct
+
+	l := parseLimits(runtime, value.ToObject(runtime), base)
+
+	assert.Equal(t, limits{
+		readTimeout:       1500 * time.Millisecond,
+		readHeaderTimeout: 200 * time.Millisecond,
+		writeTimeout:      2500 * time.Microsecond,
+		idleTimeout:       time.Minute,
+		shutdownTimeout:   durationOf(2 * time.Second),
+		maxHeaderBytes:    4096,
+	}, l)
+
+	assert.Equal(t, base, parseLimits(runtime, runtime.NewObject(), base))
+
+	for _, src := range []string{`({ readTimeout: "foo" })`, `({ idleTimeout: -1 })`, `({ writeTimeout: true })`, `({ maxHeaderBytes: -1 })`} {
+		value, err := runtime.RunString(src)
+
+		assert.NoError(t, err)
+		assert.Panics(t, func() { parseLimits(runtime, value.ToObject(runtime), base) }, src)
+	}
+}
+
+func Test_limits_apply(t *testing.T) {
+	t.Parallel()
+
+	srv := new(http.Server)
+
+	limits{ //nolint:exhaustruct
+		readTimeout:       time.Second,
+		readHeaderTimeout: 2 * time.Second,
+		writeTimeout:      3 * time.Second,
+		idleTimeout:       4 * time.Second,
+		maxHeaderBytes:    1024,
+	}.apply(srv)
+
+	assert.Equal(t, time.Second, srv.ReadTimeout)
+	assert.Equal(t, 2*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 4*time.Second, srv.IdleTimeout)
+	assert.Equal(t, 1024, srv.MaxHeaderBytes)
+
+	assert.Equal(t, defaultShutdownTimeout, limits{}.shutdown())                              //nolint:exhaustruct
+	assert.Equal(t, time.Second, limits{shutdownTimeout: durationOf(time.Second)}.shutdown()) //nolint:exhaustruct
+	assert.Equal(t, time.Duration(0), limits{shutdownTimeout: durationOf(0)}.shutdown())      //nolint:exhaustruct
+}
+
+func durationOf(d time.Duration) *time.Duration {
+	return &d
+}
+
+func Test_application_listen_limits(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime, WithReadHeaderTimeout(time.Hour), WithMaxHeaderBytes(1))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	host, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("ok"))
+	app.listen({ host: "127.0.0.1", readHeaderTimeout: 50, maxHeaderBytes: 512 })
+	app.host
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	conn, err := net.Dial("tcp", host.String())
+
+	assert.NoError(t, err)
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = io.ReadAll(conn)
+
+	assert.NoError(t, err, "connection should be closed by the server after the read header timeout")
+
+	conn, err = net.Dial("tcp", host.String())
+
+	assert.NoError(t, err)
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nX-Large: " + strings.Repeat("x", 8192) + "\r\n\r\n"))
+
+	assert.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+
+	assert.NoError(t, err)
+
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
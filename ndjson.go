@@ -0,0 +1,344 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+var errIteratorResult = errors.New("iterator result is not an object")
+
+// Encoded items of JSON streams are buffered, the buffer is flushed to the client when it is full,
+// when the stream has not been flushed for the flush interval, before awaiting a promise and at the end.
+const (
+	jsonStreamBufferSize    = 32 * 1024
+	jsonStreamFlushInterval = 100 * time.Millisecond
+)
+
+// ndjson streams the items of an iterable as newline delimited JSON, one item per line.
+func (resp *response) ndjson(iterable goja.Value) *goja.Object {
+	resp.Header().Set("Content-Type", "application/x-ndjson")
+
+	stream := newJSONStream(resp, iterable)
+
+	stream.encode = func(item goja.Value) ([]byte, error) {
+		bin, err := json.Marshal(item.Export())
+		if err != nil {
+			return nil, err
+		}
+
+		return append(bin, '\n'), nil
+	}
+
+	stream.start()
+
+	return resp.this
+}
+
+// jsonArray streams the items of an iterable as JSON array, encoding the items one at a time.
+func (resp *response) jsonArray(iterable goja.Value) *goja.Object {
+	resp.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	stream := newJSONStream(resp, iterable)
+
+	stream.prefix, stream.separator, stream.suffix = []byte("["), []byte(","), []byte("]")
+	stream.encode = func(item goja.Value) ([]byte, error) {
+		return json.Marshal(item.Export())
+	}
+
+	stream.start()
+
+	return resp.this
+}
+
+// jsonStream writes the items of a sync or async iterable to a streaming response.
+// Promises returned by the next method of the iterator (and promise items) are awaited without blocking,
+// so the items are never materialized together in the JavaScript heap.
+//
+// The status and the header are sent before the first item, so a failure during the iteration can only end
+// the response early: the client receives the items written so far. After the first awaited promise the failure
+// can not be thrown to the route handler either, so it is logged.
+type jsonStream struct {
+	resp     *response
+	runtime  *goja.Runtime
+	iterator *goja.Object
+	next     goja.Callable
+
+	encode    func(item goja.Value) ([]byte, error)
+	prefix    []byte
+	separator []byte
+	suffix    []byte
+
+	buff    *bufio.Writer
+	flushed time.Time
+
+	count int
+	async bool
+}
+
+func newJSONStream(resp *response, iterable goja.Value) *jsonStream {
+	stream := &jsonStream{resp: resp, runtime: resp.runtime} //nolint:exhaustruct
+
+	stream.buff = bufio.NewWriterSize(streamWriter{resp: resp}, jsonStreamBufferSize)
+
+	stream.iterator = getIterator(resp.runtime, iterable)
+
+	next, ok := goja.AssertFunction(stream.iterator.Get("next"))
+	if !ok {
+		throwf(resp.runtime, "iterator has no next method")
+	}
+
+	stream.next = next
+
+	return stream
+}
+
+// getIterator returns the iterator of an async iterable, a sync iterable or the value itself if it is an iterator.
+func getIterator(runtime *goja.Runtime, iterable goja.Value) *goja.Object {
+	obj, ok := iterable.(*goja.Object)
+	if !ok || obj == nil {
+		throwf(runtime, "value is not iterable")
+	}
+
+	fn, ok := goja.AssertFunction(iteratorMethod(runtime, obj))
+	if !ok {
+		if _, isIterator := goja.AssertFunction(obj.Get("next")); isIterator {
+			return obj
+		}
+
+		throwf(runtime, "value is not iterable")
+	}
+
+	value, err := fn(obj)
+
+	must(runtime, err)
+
+	iterator, ok := value.(*goja.Object)
+	if !ok || iterator == nil {
+		throwf(runtime, "iterator is not an object")
+	}
+
+	return iterator
+}
+
+// iteratorMethod returns the Symbol.asyncIterator method of the object, or the Symbol.iterator method if it is missing.
+func iteratorMethod(runtime *goja.Runtime, obj *goja.Object) goja.Value {
+	var method goja.Value
+
+	if sym, ok := runtime.GlobalObject().Get("Symbol").ToObject(runtime).Get("asyncIterator").(*goja.Symbol); ok {
+		method = obj.GetSymbol(sym)
+	}
+
+	if !isSet(method) {
+		method = obj.GetSymbol(goja.SymIterator)
+	}
+
+	return method
+}
+
+// isIterator returns true for iterable iterators, like generator objects: objects with next method
+// and Symbol.iterator or Symbol.asyncIterator method. Other objects with next property (like pagination objects)
+// are not iterators, neither are arrays.
+func isIterator(runtime *goja.Runtime, value goja.Value) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok || obj == nil || isArray(obj) {
+		return false
+	}
+
+	if _, ok := goja.AssertFunction(iteratorMethod(runtime, obj)); !ok {
+		return false
+	}
+
+	_, ok = goja.AssertFunction(obj.Get("next"))
+
+	return ok
+}
+
+func (stream *jsonStream) start() {
+	stream.resp.flush()
+	stream.flushed = time.Now()
+
+	if len(stream.prefix) != 0 {
+		stream.write(stream.prefix)
+	}
+
+	stream.pump()
+}
+
+// pump reads the iterator until it is exhausted or a promise has to be awaited.
+func (stream *jsonStream) pump() {
+	for {
+		if stream.resp.gone() {
+			stream.cancel()
+
+			return
+		}
+
+		result, err := stream.next(stream.iterator)
+		if err != nil {
+			stream.fail(err)
+		}
+
+		if stream.await(result, stream.onResult) {
+			return
+		}
+
+		if !stream.onResult(result) {
+			return
+		}
+	}
+}
+
+// onResult handles an iterator result and returns true if the iteration can go on synchronously.
+func (stream *jsonStream) onResult(result goja.Value) bool {
+	obj, ok := result.(*goja.Object)
+	if !ok || obj == nil {
+		stream.fail(errIteratorResult)
+	}
+
+	if isTrue(obj.Get("done")) {
+		if len(stream.suffix) != 0 {
+			stream.write(stream.suffix)
+		}
+
+		stream.end()
+
+		return false
+	}
+
+	item := obj.Get("value")
+	if item == nil {
+		item = goja.Undefined()
+	}
+
+	if stream.await(item, stream.onItem) {
+		return false
+	}
+
+	return stream.onItem(item)
+}
+
+// onItem writes an item and returns true if the iteration can go on synchronously.
+func (stream *jsonStream) onItem(item goja.Value) bool {
+	bin, err := stream.encode(item)
+	if err != nil {
+		stream.fail(err)
+	}
+
+	if stream.count != 0 && len(stream.separator) != 0 {
+		bin = append(append(make([]byte, 0, len(stream.separator)+len(bin)), stream.separator...), bin...)
+	}
+
+	stream.count++
+
+	stream.write(bin)
+
+	return true
+}
+
+// await registers the handler on a thenable value and returns true, otherwise it returns false.
+// The iteration continues with pump if the handler allows it.
+func (stream *jsonStream) await(value goja.Value, handler func(goja.Value) bool) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok || obj == nil {
+		return false
+	}
+
+	then, ok := goja.AssertFunction(obj.Get("then"))
+	if !ok {
+		return false
+	}
+
+	stream.flush()
+
+	onFulfilled := func(call goja.FunctionCall) goja.Value {
+		stream.async = true
+
+		if handler(call.Argument(0)) {
+			stream.pump()
+		}
+
+		return goja.Undefined()
+	}
+
+	onRejected := func(call goja.FunctionCall) goja.Value {
+		stream.async = true
+
+		stream.resp.logger.WithField("reason", call.Argument(0).String()).Error("JSON stream aborted, promise rejected")
+		stream.end()
+
+		return goja.Undefined()
+	}
+
+	_, err := then(obj, stream.runtime.ToValue(onFulfilled), stream.runtime.ToValue(onRejected))
+	if err != nil {
+		stream.fail(err)
+	}
+
+	return true
+}
+
+// write buffers the data, the buffer is flushed if it is full or the flush interval is elapsed.
+func (stream *jsonStream) write(data []byte) {
+	if _, err := stream.buff.Write(data); err != nil {
+		stream.fail(err)
+	}
+
+	if time.Since(stream.flushed) >= jsonStreamFlushInterval {
+		stream.flush()
+	}
+}
+
+func (stream *jsonStream) flush() {
+	stream.flushed = time.Now()
+
+	if err := stream.buff.Flush(); err != nil {
+		stream.fail(err)
+	}
+}
+
+// end sends the buffered data and ends the response.
+func (stream *jsonStream) end() {
+	stream.buff.Flush() //nolint:errcheck
+
+	stream.resp.end(goja.Undefined())
+}
+
+// streamWriter writes the data as a chunk of the streaming response, flushing it to the client.
+type streamWriter struct {
+	resp *response
+}
+
+func (w streamWriter) Write(data []byte) (int, error) {
+	if err := w.resp.stream(data); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// cancel closes the iterator after the client is gone, so generators can release their resources.
+func (stream *jsonStream) cancel() {
+	if fn, ok := goja.AssertFunction(stream.iterator.Get("return")); ok {
+		fn(stream.iterator) //nolint:errcheck
+	}
+}
+
+// fail ends the (already started) response and throws the error.
+// In promise callbacks the error would be lost, so it is logged as well.
+func (stream *jsonStream) fail(err error) {
+	stream.end()
+
+	if stream.async {
+		stream.resp.logger.WithError(err).Error("JSON stream aborted")
+	}
+
+	throw(stream.runtime, err)
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStreamTest(t *testing.T, script string) (*goja.Runtime, *httptest.ResponseRecorder, *response, *goja.Object, goja.Value) {
+	t.Helper()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+
+	value, err := runtime.RunString(script)
+
+	assert.NoError(t, err)
+
+	return runtime, rec, res, obj, value
+}
+
+const asyncIterator = `({
+	i: 0,
+	next() {
+		return Promise.resolve(this.i < 3 ? { value: Promise.resolve({ n: this.i++ }), done: false } : { done: true })
+	}
+})`
+
+func Test_response_ndjson(t *testing.T) {
+	t.Parallel()
+
+	for name, script := range map[string]string{
+		"generator": `(function* () { for (let n = 0; n < 3; n++) yield { n } })()`,
+		"array":     `([{ n: 0 }, { n: 1 }, { n: 2 }])`,
+		"async":     asyncIterator,
+	} {
+		_, rec, res, obj, value := newStreamTest(t, script)
+
+		callMethod(t, obj, "ndjson", value)
+
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"), name)
+		assert.Equal(t, "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n", rec.Body.String(), name)
+		assert.True(t, rec.Flushed, name)
+		assert.True(t, res.gone(), name)
+	}
+}
+
+func Test_response_jsonArray(t *testing.T) {
+	t.Parallel()
+
+	for name, script := range map[string]string{
+		"empty":     `([])`,
+		"generator": `(function* () { yield 1; yield "two"; yield { three: 3 } })()`,
+		"async":     asyncIterator,
+	} {
+		_, rec, res, obj, value := newStreamTest(t, script)
+
+		callMethod(t, obj, "jsonArray", value)
+
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"), name)
+		assert.True(t, res.gone(), name)
+
+		switch name {
+		case "empty":
+			assert.Equal(t, `[]`, rec.Body.String())
+		case "generator":
+			assert.Equal(t, `[1,"two",{"three":3}]`, rec.Body.String())
+		default:
+			assert.Equal(t, `[{"n":0},{"n":1},{"n":2}]`, rec.Body.String())
+		}
+	}
+
+	_, rec, _, obj, value := newStreamTest(t, `(function* () { yield 1; yield 2 })()`)
+
+	callMethod(t, obj, "json", value)
+
+	assert.Equal(t, `[1,2]`, rec.Body.String())
+
+	_, rec, _, obj, value = newStreamTest(t, `({ next: 1 })`)
+
+	callMethod(t, obj, "send", value)
+
+	assert.Equal(t, `{"next":1}`, rec.Body.String())
+
+	_, rec, _, obj, value = newStreamTest(t, `new (class Page { constructor() { this.items = [1] } next() { return {} } })()`)
+
+	callMethod(t, obj, "json", value)
+
+	assert.Equal(t, `{"items":[1]}`, rec.Body.String())
+}
+
+// flushCounter counts the flushes of the response.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (rec *flushCounter) Flush() {
+	rec.flushes++
+
+	rec.ResponseRecorder.Flush()
+}
+
+func Test_response_ndjson_buffered(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := &flushCounter{ResponseRecorder: httptest.NewRecorder()} //nolint:exhaustruct
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+
+	value, err := runtime.RunString(`(function* () { for (let n = 0; n < 10000; n++) yield n })()`)
+
+	assert.NoError(t, err)
+
+	callMethod(t, obj, "ndjson", value)
+
+	assert.True(t, res.gone())
+	assert.Len(t, strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n"), 10000)
+	assert.Less(t, rec.flushes, 10)
+}
+
+func Test_response_ndjson_error(t *testing.T) {
+	t.Parallel()
+
+	runtime, rec, res, obj, value := newStreamTest(t, `(function* () { yield 1; throw new Error("boom") })()`)
+
+	fn, _ := goja.AssertFunction(obj.Get("ndjson"))
+
+	_, err := fn(obj, value)
+
+	assert.ErrorContains(t, err, "boom")
+	assert.Equal(t, "1\n", rec.Body.String())
+	assert.True(t, res.gone())
+
+	_, err = fn(obj, runtime.ToValue(42))
+
+	assert.ErrorContains(t, err, "not iterable")
+
+	_, rec, res, obj, value = newStreamTest(t, `({ next: () => Promise.reject(new Error("boom")) })`)
+
+	logger, hook := test.NewNullLogger()
+	res.logger = logger
+
+	callMethod(t, obj, "ndjson", value)
+
+	assert.Empty(t, rec.Body.String())
+	assert.True(t, res.gone())
+	assert.Equal(t, "JSON stream aborted, promise rejected", hook.LastEntry().Message)
+	assert.Equal(t, "Error: boom", hook.LastEntry().Data["reason"])
+
+	_, rec, res, obj, value = newStreamTest(t, `({
+		i: 0,
+		next() {
+			return Promise.resolve(this.i++ < 1 ? { value: 1, done: false } : { value: () => {}, done: false })
+		}
+	})`)
+
+	logger, hook = test.NewNullLogger()
+	res.logger = logger
+
+	callMethod(t, obj, "jsonArray", value)
+
+	assert.Equal(t, "[1", rec.Body.String())
+	assert.True(t, res.gone())
+	assert.Equal(t, "JSON stream aborted", hook.LastEntry().Message)
+}
+
+func Test_response_ndjson_gone(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+
+	assert.NoError(t, runtime.Set("disconnect", func() {
+		res.mu.Lock()
+		res.closed = true
+		res.mu.Unlock()
+	}))
+
+	value, err := runtime.RunString(`
+	var returned = false
+
+	;(function* () {
+		try {
+			for (let n = 0; ; n++) {
+				if (n == 2) disconnect()
+				yield n
+			}
+		} finally {
+			returned = true
+		}
+	})()
+	`)
+
+	assert.NoError(t, err)
+
+	callMethod(t, obj, "ndjson", value)
+
+	assert.NotContains(t, rec.Body.String(), "2\n")
+	assert.True(t, runtime.Get("returned").ToBoolean())
+}
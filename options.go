@@ -6,8 +6,11 @@ package muxpress
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/sirupsen/logrus"
@@ -22,6 +25,18 @@ type options struct {
 	logger     logrus.FieldLogger
 	filesystem afero.Fs
 	context    func() context.Context
+	secrets    []string
+	tlsConfig  *tls.Config
+	authority  *CertificateAuthority
+	limits     limits
+
+	// synchronous is true for the default runner, when there is no event loop.
+	synchronous bool
+
+	// thread runs the JavaScript code of the applications via runner.
+	thread *jsThread
+
+	httpMiddlewares []func(http.Handler) http.Handler
 }
 
 func getopts(with ...Option) (*options, error) {
@@ -46,12 +61,15 @@ func getopts(with ...Option) (*options, error) {
 
 	if opts.runner == nil {
 		opts.runner = syncRunner()
+		opts.synchronous = true
 	}
 
 	if opts.context == nil {
 		opts.context = context.TODO
 	}
 
+	opts.thread = newJSThread(opts.runner, opts.synchronous, opts.logger)
+
 	return opts, nil
 }
 
@@ -80,6 +98,90 @@ func WithContext(context func() context.Context) Option {
 	}
 }
 
+// WithSecret returns an Option that specifies secrets to be used for signing and verifying cookies.
+// Cookies are signed with the first secret, while all secrets are tried when verifying signed cookies.
+// This allows secret rotation.
+func WithSecret(secret ...string) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secret...)
+	}
+}
+
+// WithTLSConfig returns an Option that specifies a [tls.Config] to be used for listening on HTTPS.
+// When specified, the application accepts only HTTPS connections. Certificates given in the tls listen option
+// are added to a clone of this configuration.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithCertificateAuthority returns an Option that specifies a [CertificateAuthority] to be used for issuing certificates
+// of self-signed HTTPS listeners. Default is to create a new ephemeral certificate authority for every application.
+func WithCertificateAuthority(ca *CertificateAuthority) Option {
+	return func(o *options) {
+		o.authority = ca
+	}
+}
+
+// WithReadTimeout returns an Option that specifies the maximum duration for reading the entire request, including the body.
+// Default is no timeout. The readTimeout listen option overrides it.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.limits.readTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout returns an Option that specifies the amount of time allowed to read request headers.
+// Default is the read timeout. The readHeaderTimeout listen option overrides it.
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.limits.readHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout returns an Option that specifies the maximum duration before timing out writes of the response.
+// Default is no timeout. The writeTimeout listen option overrides it.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.limits.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout returns an Option that specifies the maximum amount of time to wait for the next request on keep-alive connections.
+// Default is the read timeout. The idleTimeout listen option overrides it.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.limits.idleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes returns an Option that specifies the maximum number of bytes of request headers, including the request line.
+// Default is [http.DefaultMaxHeaderBytes]. The maxHeaderBytes listen option overrides it.
+func WithMaxHeaderBytes(size int) Option {
+	return func(o *options) {
+		o.limits.maxHeaderBytes = size
+	}
+}
+
+// WithShutdownTimeout returns an Option that specifies the maximum duration of waiting for active connections on shutdown.
+// Zero means that active connections are closed immediately, without draining.
+// Default is 500 milliseconds. The shutdownTimeout listen option overrides it.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.limits.shutdownTimeout = &timeout
+	}
+}
+
+// WithHTTPMiddleware returns an Option that specifies Go HTTP middlewares to be wrapped around the application's request handler.
+// Middlewares are applied in the given order, so the first one will be the outermost.
+// Use [ContextWithValues] in a middleware to pass per-request values to JavaScript middlewares.
+func WithHTTPMiddleware(middleware ...func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, middleware...)
+	}
+}
+
 // WithRunner returns an Option that specifies a runner function to be used for execute middlewares for incoming requests.
 // This option allows you to schedule middleware calls in the event loop.
 //
@@ -6,9 +6,12 @@ package muxpress
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 	"reflect"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/sirupsen/logrus"
@@ -48,6 +51,39 @@ func Test_With(t *testing.T) {
 	WithRunner(runner)(opts)
 	assertRunnerFuncEqual(t, runner, opts.runner)
 
+	WithSecret("foo", "bar")(opts)
+	assert.Equal(t, []string{"foo", "bar"}, opts.secrets)
+
+	middleware := func(next http.Handler) http.Handler { return next }
+
+	WithHTTPMiddleware(middleware, middleware)(opts)
+	assert.Len(t, opts.httpMiddlewares, 2)
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13} //nolint:exhaustruct
+
+	WithTLSConfig(tlsConfig)(opts)
+	assert.Same(t, tlsConfig, opts.tlsConfig)
+
+	ca := new(CertificateAuthority)
+
+	WithCertificateAuthority(ca)(opts)
+	assert.Same(t, ca, opts.authority)
+
+	WithReadTimeout(time.Second)(opts)
+	WithReadHeaderTimeout(2 * time.Second)(opts)
+	WithWriteTimeout(3 * time.Second)(opts)
+	WithIdleTimeout(4 * time.Second)(opts)
+	WithShutdownTimeout(5 * time.Second)(opts)
+	WithMaxHeaderBytes(1024)(opts)
+	assert.Equal(t, limits{
+		readTimeout:       time.Second,
+		readHeaderTimeout: 2 * time.Second,
+		writeTimeout:      3 * time.Second,
+		idleTimeout:       4 * time.Second,
+		shutdownTimeout:   durationOf(5 * time.Second),
+		maxHeaderBytes:    1024,
+	}, opts.limits)
+
 	opts.runner = nil
 	WithRunOnLoop(func(f func(*goja.Runtime)) {})(opts)
 	assert.NotNil(t, opts.runner)
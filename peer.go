@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// client returns the verified client certificate of a TLS connection, or null.
+func (req *request) client() goja.Value {
+	req.clientOnce.Do(func() {
+		req.clientValue = wrapClientCertificate(req.runtime, req.TLS)
+	})
+
+	return req.clientValue
+}
+
+// wrapClientCertificate returns the leaf of the first verified chain. Unverified certificates are not exposed.
+func wrapClientCertificate(runtime *goja.Runtime, state *tls.ConnectionState) goja.Value {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return goja.Null()
+	}
+
+	cert := state.VerifiedChains[0][0]
+
+	out := runtime.NewObject()
+
+	mustSet(runtime, out, "subject", cert.Subject.String())
+	mustSet(runtime, out, "commonName", cert.Subject.CommonName)
+	mustSet(runtime, out, "issuer", cert.Issuer.String())
+	mustSet(runtime, out, "serialNumber", strings.ToUpper(cert.SerialNumber.Text(16)))
+	mustSet(runtime, out, "fingerprint", fingerprint(cert))
+	mustSet(runtime, out, "validFrom", newDate(runtime, cert.NotBefore.UnixMilli()))
+	mustSet(runtime, out, "validTo", newDate(runtime, cert.NotAfter.UnixMilli()))
+	mustSet(runtime, out, "dnsNames", newStringArray(runtime, cert.DNSNames))
+	mustSet(runtime, out, "emailAddresses", newStringArray(runtime, cert.EmailAddresses))
+
+	ips := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	mustSet(runtime, out, "ipAddresses", newStringArray(runtime, ips))
+
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	mustSet(runtime, out, "uris", newStringArray(runtime, uris))
+
+	return out
+}
+
+// fingerprint returns the SHA-256 fingerprint of the certificate as colon separated hex bytes.
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func newDate(runtime *goja.Runtime, msec int64) *goja.Object {
+	date, err := runtime.New(runtime.Get("Date"), runtime.ToValue(msec))
+
+	must(runtime, err)
+
+	return date
+}
+
+func newStringArray(runtime *goja.Runtime, values []string) *goja.Object {
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+
+	return runtime.NewArray(items...)
+}
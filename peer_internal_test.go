@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_wrapClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	ca, err := NewCertificateAuthority()
+
+	assert.NoError(t, err)
+
+	cert, err := ca.issue(x509.ExtKeyUsageClientAuth, "client.example.com", "10.0.0.1")
+
+	assert.NoError(t, err)
+
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert.Leaf, ca.cert}}} //nolint:exhaustruct
+
+	assert.NoError(t, runtime.Set("client", wrapClientCertificate(runtime, state)))
+
+	value, err := runtime.RunString(`({
+		subject: client.subject,
+		commonName: client.commonName,
+		issuer: client.issuer,
+		fingerprint: client.fingerprint,
+		dnsNames: client.dnsNames.join(),
+		ipAddresses: client.ipAddresses.join(),
+		emailAddresses: client.emailAddresses.length,
+		uris: client.uris.length,
+		valid: client.validFrom < new Date() && new Date() < client.validTo,
+	})`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"subject":        "CN=client.example.com",
+		"commonName":     "client.example.com",
+		"issuer":         "CN=" + caCommonName,
+		"fingerprint":    fingerprint(cert.Leaf),
+		"dnsNames":       "client.example.com",
+		"ipAddresses":    "10.0.0.1",
+		"emailAddresses": int64(0),
+		"uris":           int64(0),
+		"valid":          true,
+	}, value.Export())
+
+	assert.Len(t, fingerprint(cert.Leaf), 32*3-1)
+
+	assert.True(t, goja.IsNull(wrapClientCertificate(runtime, nil)))
+
+	state = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert.Leaf}} //nolint:exhaustruct
+
+	assert.True(t, goja.IsNull(wrapClientCertificate(runtime, state)))
+}
+
+func Test_request_client(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	req := wrapHTTPRequest(runtime, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.True(t, goja.IsNull(req.Get("client")))
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	errRangeMalformed     = errors.New("malformed range")
+	errRangeUnsatisfiable = errors.New("unsatisfiable range")
+)
+
+const (
+	rangeUnsatisfiableCode = -1
+	rangeMalformedCode     = -2
+)
+
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// parseRange parses Range header value the same way as the range-parser package used by Express.js does.
+// The returned ranges are inclusive and limited to size.
+func parseRange(header string, size int64, combine bool) (string, []byteRange, error) {
+	idx := strings.Index(header, "=")
+	if idx < 0 {
+		return "", nil, errRangeMalformed
+	}
+
+	unit := header[:idx]
+	ranges := make([]byteRange, 0)
+
+	for _, spec := range strings.Split(header[idx+1:], ",") {
+		parts := strings.SplitN(strings.TrimSpace(spec), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		start, startErr := strconv.ParseInt(parts[0], 10, 64)
+		end, endErr := strconv.ParseInt(parts[1], 10, 64)
+
+		switch {
+		case startErr != nil && endErr == nil:
+			start = size - end
+			end = size - 1
+		case startErr == nil && endErr != nil:
+			end = size - 1
+		case startErr != nil && endErr != nil:
+			continue
+		}
+
+		if end > size-1 {
+			end = size - 1
+		}
+
+		if start > end || start < 0 {
+			continue
+		}
+
+		ranges = append(ranges, byteRange{start: start, end: end})
+	}
+
+	if len(ranges) == 0 {
+		return unit, nil, errRangeUnsatisfiable
+	}
+
+	if combine {
+		ranges = combineRanges(ranges)
+	}
+
+	return unit, ranges, nil
+}
+
+func combineRanges(ranges []byteRange) []byteRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	out := ranges[:1]
+
+	for _, current := range ranges[1:] {
+		last := &out[len(out)-1]
+
+		if current.start > last.end+1 {
+			out = append(out, current)
+
+			continue
+		}
+
+		if current.end > last.end {
+			last.end = current.end
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRange(t *testing.T) {
+	t.Parallel()
+
+	unit, ranges, err := parseRange("bytes=0-499", 1000, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes", unit)
+	assert.Equal(t, []byteRange{{start: 0, end: 499}}, ranges)
+
+	_, ranges, err = parseRange("bytes=-500", 1000, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byteRange{{start: 500, end: 999}}, ranges)
+
+	_, ranges, err = parseRange("bytes=900-", 1000, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byteRange{{start: 900, end: 999}}, ranges)
+
+	_, ranges, err = parseRange("bytes=900-2000", 1000, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byteRange{{start: 900, end: 999}}, ranges)
+
+	_, ranges, err = parseRange("bytes=0-1, 5-10", 1000, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byteRange{{start: 0, end: 1}, {start: 5, end: 10}}, ranges)
+
+	unit, _, err = parseRange("items=0-1", 1000, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "items", unit)
+
+	_, _, err = parseRange("bytes=1000-1001", 1000, false)
+
+	assert.ErrorIs(t, err, errRangeUnsatisfiable)
+
+	_, _, err = parseRange("bytes=foo", 1000, false)
+
+	assert.ErrorIs(t, err, errRangeUnsatisfiable)
+
+	_, _, err = parseRange("bytes", 1000, false)
+
+	assert.ErrorIs(t, err, errRangeMalformed)
+}
+
+func Test_parseRange_combine(t *testing.T) {
+	t.Parallel()
+
+	_, ranges, err := parseRange("bytes=5-10,0-2,3-4,20-30", 1000, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byteRange{{start: 0, end: 10}, {start: 20, end: 30}}, ranges)
+}
@@ -6,6 +6,7 @@ package muxpress
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -16,20 +17,29 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-func wrapRequest(runtime *goja.Runtime, from *http.Request) *goja.Object {
-	req := newRequest(runtime, from)
+func wrapHTTPRequest(runtime *goja.Runtime, from *http.Request) *goja.Object {
+	return wrapRequest(runtime, newRequest(runtime, from))
+}
+
+func wrapRequest(runtime *goja.Runtime, req *request) *goja.Object {
 	this := runtime.NewObject()
 
 	mustSetGetter(runtime, this, "host", req.host)
 	mustSetGetter(runtime, this, "method", req.method)
 	mustSetGetter(runtime, this, "path", req.path)
 	mustSetGetter(runtime, this, "protocol", req.protocol)
+	mustSetGetter(runtime, this, "httpVersion", req.httpVersion)
 	mustSetGetter(runtime, this, "params", req.params)
 	mustSetGetter(runtime, this, "query", req.query)
 	mustSetGetter(runtime, this, "cookies", req.cookies)
+	mustSetGetter(runtime, this, "signedCookies", req.signedCookies)
 	mustSetGetter(runtime, this, "body", req.body)
+	mustSetGetter(runtime, this, "context", req.context)
+	mustSetGetter(runtime, this, "lastEventId", req.lastEventID)
+	mustSetGetter(runtime, this, "client", req.client)
 
 	mustSet(runtime, this, "get", req.get)
+	mustSet(runtime, this, "range", req.byteRange)
 
 	return this
 }
@@ -38,6 +48,49 @@ func (req *request) get(field string) string {
 	return req.Header.Get(field)
 }
 
+func (req *request) byteRange(size int64, options goja.Value) goja.Value {
+	header := req.Header.Get("Range")
+	if len(header) == 0 {
+		return goja.Undefined()
+	}
+
+	combine := false
+
+	if obj, ok := options.(*goja.Object); ok {
+		combine = isTrue(obj.Get("combine"))
+	}
+
+	unit, ranges, err := parseRange(header, size, combine)
+
+	switch {
+	case errors.Is(err, errRangeMalformed):
+		return req.runtime.ToValue(rangeMalformedCode)
+	case errors.Is(err, errRangeUnsatisfiable):
+		return req.runtime.ToValue(rangeUnsatisfiableCode)
+	}
+
+	all := make([]interface{}, 0, len(ranges))
+
+	for _, r := range ranges {
+		obj := req.runtime.NewObject()
+
+		mustSet(req.runtime, obj, "start", r.start)
+		mustSet(req.runtime, obj, "end", r.end)
+
+		all = append(all, obj)
+	}
+
+	arr := req.runtime.NewArray(all...)
+
+	mustSet(req.runtime, arr, "type", unit)
+
+	return arr
+}
+
+func (req *request) lastEventID() string {
+	return req.Header.Get("Last-Event-ID")
+}
+
 func (req *request) host() string {
 	return req.Host
 }
@@ -50,13 +103,23 @@ func (req *request) path() string {
 	return req.URL.Path
 }
 
+// protocol returns the request protocol: https for TLS connections, http otherwise.
 func (req *request) protocol() string {
-	return req.URL.Scheme
+	if len(req.URL.Scheme) != 0 {
+		return req.URL.Scheme
+	}
+
+	if req.TLS != nil {
+		return "https"
+	}
+
+	return "http"
 }
 
 type request struct {
 	*http.Request
 	runtime *goja.Runtime
+	secrets []string
 
 	paramsOnce sync.Once
 	paramsObj  *goja.Object
@@ -67,8 +130,17 @@ type request struct {
 	cookiesOnce sync.Once
 	cookiesObj  *goja.Object
 
+	signedCookiesOnce sync.Once
+	signedCookiesObj  *goja.Object
+
 	bodyOnce  sync.Once
 	bodyValue goja.Value
+
+	contextOnce sync.Once
+	contextObj  *goja.Object
+
+	clientOnce  sync.Once
+	clientValue goja.Value
 }
 
 func newRequest(runtime *goja.Runtime, req *http.Request) *request {
@@ -93,12 +165,26 @@ func (req *request) query() *goja.Object {
 
 func (req *request) cookies() *goja.Object {
 	req.cookiesOnce.Do(func() {
-		req.cookiesObj = wrapCookies(req.runtime, req.Cookies())
+		cookies := req.Cookies()
+
+		if len(req.secrets) != 0 {
+			cookies = unsignedCookies(cookies)
+		}
+
+		req.cookiesObj = wrapCookies(req.runtime, cookies)
 	})
 
 	return req.cookiesObj
 }
 
+func (req *request) signedCookies() *goja.Object {
+	req.signedCookiesOnce.Do(func() {
+		req.signedCookiesObj = wrapSignedCookies(req.runtime, req.Cookies(), req.secrets)
+	})
+
+	return req.signedCookiesObj
+}
+
 func (req *request) body() goja.Value {
 	req.bodyOnce.Do(func() {
 		req.bodyValue = wrapBody(req.runtime, req.Request)
@@ -107,6 +193,14 @@ func (req *request) body() goja.Value {
 	return req.bodyValue
 }
 
+func (req *request) context() *goja.Object {
+	req.contextOnce.Do(func() {
+		req.contextObj = wrapContext(req.runtime, req.Context())
+	})
+
+	return req.contextObj
+}
+
 func wrapValues(runtime *goja.Runtime, values url.Values) *goja.Object {
 	out := runtime.NewObject()
 
@@ -145,14 +239,55 @@ func wrapCookies(runtime *goja.Runtime, cookies []*http.Cookie) *goja.Object {
 	out := runtime.NewObject()
 
 	for _, c := range cookies {
-		mustSet(runtime, out, c.Name, c.Value)
+		mustSet(runtime, out, c.Name, parseCookieValue(runtime, decodeCookieValue(c.Value)))
+	}
+
+	return out
+}
+
+func wrapSignedCookies(runtime *goja.Runtime, cookies []*http.Cookie, secrets []string) *goja.Object {
+	out := runtime.NewObject()
+
+	if len(secrets) == 0 {
+		return out
+	}
+
+	for _, c := range cookies {
+		decoded := decodeCookieValue(c.Value)
+
+		if !strings.HasPrefix(decoded, signedCookiePrefix) {
+			continue
+		}
+
+		if value, ok := unsignCookie(decoded[len(signedCookiePrefix):], secrets); ok {
+			mustSet(runtime, out, c.Name, parseCookieValue(runtime, value))
+		} else {
+			mustSet(runtime, out, c.Name, false)
+		}
+	}
+
+	return out
+}
+
+func unsignedCookies(cookies []*http.Cookie) []*http.Cookie {
+	out := make([]*http.Cookie, 0, len(cookies))
+
+	for _, c := range cookies {
+		if !strings.HasPrefix(decodeCookieValue(c.Value), signedCookiePrefix) {
+			out = append(out, c)
+		}
 	}
 
 	return out
 }
 
 func wrapBody(runtime *goja.Runtime, req *http.Request) goja.Value {
-	if req.ContentLength == 0 || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+	ctype := req.Header.Get("Content-Type")
+	isJSON := strings.HasPrefix(ctype, "application/json")
+	isBinary := strings.HasPrefix(ctype, "application/octet-stream")
+	isXML := isXMLType(ctype)
+
+	if req.ContentLength == 0 || (!isJSON && !isBinary && !isXML) {
 		return goja.Undefined()
 	}
 
@@ -163,6 +298,18 @@ func wrapBody(runtime *goja.Runtime, req *http.Request) goja.Value {
 		throw(runtime, err)
 	}
 
+	if isBinary {
+		return runtime.ToValue(runtime.NewArrayBuffer(bin))
+	}
+
+	if isXML {
+		out, err := unmarshalXML(bin)
+
+		must(runtime, err)
+
+		return runtime.ToValue(out)
+	}
+
 	out := map[string]interface{}{}
 
 	must(runtime, json.Unmarshal(bin, &out))
@@ -93,6 +93,30 @@ func Test_request_cookies(t *testing.T) {
 	assert.NotNil(t, req.cookies())
 }
 
+func Test_request_signedCookies(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+	from.AddCookie(&http.Cookie{Name: "plain", Value: "plain-value"})                                  //nolint:exhaustruct
+	from.AddCookie(&http.Cookie{Name: "signed", Value: "s:" + signCookie("signed-value", "secret")})   //nolint:exhaustruct
+	from.AddCookie(&http.Cookie{Name: "tampered", Value: "s:" + signCookie("signed-value", "foobar")}) //nolint:exhaustruct
+
+	req := newRequest(runtime, from)
+
+	assert.Empty(t, req.signedCookies().Keys())
+	assert.Equal(t, 3, len(req.cookies().Keys()))
+
+	req = newRequest(runtime, from)
+	req.secrets = []string{"secret"}
+
+	assert.Equal(t, []string{"plain"}, req.cookies().Keys())
+	assert.Equal(t, "signed-value", req.signedCookies().Get("signed").String())
+	assert.Equal(t, false, req.signedCookies().Get("tampered").Export())
+	assert.Nil(t, req.signedCookies().Get("plain"))
+}
+
 func Test_request_query(t *testing.T) {
 	t.Parallel()
 
@@ -109,6 +133,21 @@ func Test_request_query(t *testing.T) {
 	assert.NotNil(t, req.query())
 }
 
+func Test_request_protocol(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	assert.Equal(t, "http", newRequest(runtime, from).protocol())
+
+	from = httptest.NewRequest(http.MethodGet, "https://localhost/", nil)
+	from.URL.Scheme = ""
+
+	assert.Equal(t, "https", newRequest(runtime, from).protocol())
+}
+
 func Test_request_params(t *testing.T) {
 	t.Parallel()
 
@@ -131,6 +170,67 @@ func Test_request_params(t *testing.T) {
 	assert.NotNil(t, req.params())
 }
 
+func Test_request_context(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+	req := newRequest(runtime, from)
+
+	assert.NotNil(t, req.context())
+	assert.Empty(t, req.context().Keys())
+
+	ctx := ContextWithValues(context.TODO(), map[string]interface{}{"foo": "bar"})
+
+	req = newRequest(runtime, from.WithContext(ctx))
+
+	assert.Equal(t, "bar", req.context().Get("foo").String())
+	assert.Same(t, req.context(), req.context())
+}
+
+func Test_request_range(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+	req := newRequest(runtime, from)
+
+	assert.True(t, goja.IsUndefined(req.byteRange(1000, nil)))
+
+	from.Header.Set("Range", "bytes=0-1,2-3")
+
+	arr, isObject := req.byteRange(1000, nil).(*goja.Object)
+
+	assert.True(t, isObject, "range must be object")
+	assert.Equal(t, "bytes", arr.Get("type").String())
+	assert.Equal(t, int64(2), arr.Get("length").ToInteger())
+
+	first, isObject := arr.Get("0").(*goja.Object)
+
+	assert.True(t, isObject)
+	assert.Equal(t, int64(0), first.Get("start").ToInteger())
+	assert.Equal(t, int64(1), first.Get("end").ToInteger())
+
+	options := runtime.NewObject()
+
+	assert.NoError(t, options.Set("combine", true))
+
+	arr, isObject = req.byteRange(1000, options).(*goja.Object)
+
+	assert.True(t, isObject, "range must be object")
+	assert.Equal(t, int64(1), arr.Get("length").ToInteger())
+
+	from.Header.Set("Range", "bytes=2000-")
+
+	assert.Equal(t, int64(rangeUnsatisfiableCode), req.byteRange(1000, nil).ToInteger())
+
+	from.Header.Set("Range", "bytes")
+
+	assert.Equal(t, int64(rangeMalformedCode), req.byteRange(1000, nil).ToInteger())
+}
+
 func Test_wrap_request(t *testing.T) {
 	t.Parallel()
 
@@ -148,7 +248,7 @@ func Test_wrap_request(t *testing.T) {
 
 	from = from.WithContext(ctx)
 
-	req := wrapRequest(runtime, from)
+	req := wrapHTTPRequest(runtime, from)
 
 	obj, isObject := req.Get("body").(*goja.Object)
 
@@ -296,6 +396,24 @@ func Test_wrapBody(t *testing.T) {
 
 	assert.NotNil(t, val)
 	assert.True(t, goja.IsUndefined(val))
+
+	from = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte{1, 2, 3}))
+
+	from.Header.Add("content-type", "application/octet-stream")
+
+	buff, isArrayBuffer := wrapBody(runtime, from).Export().(goja.ArrayBuffer)
+
+	assert.True(t, isArrayBuffer)
+	assert.Equal(t, []byte{1, 2, 3}, buff.Bytes())
+
+	from = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<user id="1"><name>Joe</name></user>`))
+
+	from.Header.Add("content-type", "text/xml; charset=utf-8")
+
+	assert.Equal(t,
+		map[string]interface{}{"user": map[string]interface{}{"@id": "1", "name": "Joe"}},
+		wrapBody(runtime, from).Export(),
+	)
 }
 
 type errReader struct{}
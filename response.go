@@ -5,21 +5,32 @@
 package muxpress
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
-func wrapResponseWriter(runtime *goja.Runtime, from http.ResponseWriter) *goja.Object {
-	return wrapResponse(runtime, newResponse(runtime, from))
+func wrapResponseWriter(runtime *goja.Runtime, from http.ResponseWriter, req *http.Request) *goja.Object {
+	return wrapResponse(runtime, newResponse(runtime, from, req))
 }
 
 func wrapResponse(runtime *goja.Runtime, resp *response) *goja.Object {
 	this := runtime.NewObject()
 
-	mustSet(runtime, this, "json", resp.json)
+	resp.this = this
+
+	mustSet(runtime, this, "json", resp.sendJSON)
 	mustSet(runtime, this, "text", resp.textf)
 	mustSet(runtime, this, "html", resp.html)
 	mustSet(runtime, this, "binary", resp.binary)
@@ -29,88 +40,626 @@ func wrapResponse(runtime *goja.Runtime, resp *response) *goja.Object {
 	mustSet(runtime, this, "vary", resp.vary)
 	mustSet(runtime, this, "set", resp.set)
 	mustSet(runtime, this, "append", resp.append)
+	mustSet(runtime, this, "getHeader", resp.getHeader)
+	mustSet(runtime, this, "removeHeader", resp.removeHeader)
 	mustSet(runtime, this, "redirect", resp.redirect)
+	mustSet(runtime, this, "cookie", resp.cookie)
+	mustSet(runtime, this, "clearCookie", resp.clearCookie)
+	mustSet(runtime, this, "write", resp.writeChunk)
+	mustSet(runtime, this, "end", resp.end)
+	mustSet(runtime, this, "flush", resp.flush)
+	mustSet(runtime, this, "sse", resp.sse)
+	mustSet(runtime, this, "sendFile", resp.sendFile)
+	mustSet(runtime, this, "download", resp.download)
+	mustSet(runtime, this, "attachment", resp.attachment)
+	mustSet(runtime, this, "render", resp.render)
+	mustSet(runtime, this, "sendStatus", resp.sendStatus)
+	mustSet(runtime, this, "jsonp", resp.jsonp)
+	mustSet(runtime, this, "links", resp.links)
+	mustSet(runtime, this, "location", resp.location)
+	mustSet(runtime, this, "xml", resp.xml)
+	mustSet(runtime, this, "ndjson", resp.ndjson)
+	mustSet(runtime, this, "jsonArray", resp.jsonArray)
+
+	mustSetGetter(runtime, this, "headersSent", resp.isHeadersSent)
+
+	mustSet(runtime, this, "locals", runtime.NewObject())
 
 	return this
 }
 
+var errWriteAfterEnd = errors.New("write after end")
+
+const (
+	settingJSONPCallbackName = "jsonp callback name"
+	defaultJSONPCallbackName = "callback"
+)
+
+var jsonpCallbackRE = regexp.MustCompile(`[^\[\]\w$.]`)
+
 type response struct {
 	http.ResponseWriter
 	runtime *goja.Runtime
+	request *http.Request
+	secrets []string
+	runner  RunnerFunc
+	logger  logrus.FieldLogger
+	this    *goja.Object
+
+	filesystem afero.Fs
+	views      *views
+	settings   map[string]interface{}
+
+	code        int
+	headersSent bool
+
+	mu        sync.Mutex
+	streaming bool
+	ended     bool
+	closed    bool
+	done      chan struct{}
+	onEnd     []func() error
+}
+
+func newResponse(runtime *goja.Runtime, writer http.ResponseWriter, req *http.Request) *response {
+	return &response{ //nolint:exhaustruct
+		ResponseWriter: writer,
+		runtime:        runtime,
+		request:        req,
+		logger:         logrus.StandardLogger(),
+		done:           make(chan struct{}),
+	}
+}
+
+// WriteHeader sends the response header only once, subsequent calls are ignored.
+func (resp *response) WriteHeader(code int) {
+	if resp.headersSent {
+		return
+	}
+
+	resp.headersSent = true
+
+	resp.ResponseWriter.WriteHeader(code)
+}
+
+// Write sends the buffered status and header before the first body write.
+// The body is discarded for status codes which do not allow it (like 204 and 304), as Node.js does.
+func (resp *response) Write(data []byte) (int, error) {
+	resp.writeHeader()
+
+	if !bodyAllowed(resp.code) {
+		return len(data), nil
+	}
+
+	return resp.ResponseWriter.Write(data)
 }
 
-func newResponse(runtime *goja.Runtime, writer http.ResponseWriter) *response {
-	return &response{ResponseWriter: writer, runtime: runtime}
+func bodyAllowed(code int) bool {
+	return !(code >= 100 && code <= 199) && code != http.StatusNoContent && code != http.StatusNotModified
 }
 
-func (resp *response) json(v interface{}) {
+// writeHeader sends the buffered status (default 200) and header fields if not yet sent.
+func (resp *response) writeHeader() {
+	if resp.headersSent {
+		return
+	}
+
+	code := resp.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	resp.WriteHeader(code)
+}
+
+// wait blocks until the response is completed or the client is gone, then calls the hooks registered by onEnded.
+// Like in Express.js, the response is not completed when the middleware chain returns, handlers may end it later
+// (from timers). After returning, the underlying writer is no longer used.
+func (resp *response) wait() {
+	var gone <-chan struct{}
+
+	if resp.request != nil {
+		gone = resp.request.Context().Done()
+	}
+
+	select {
+	case <-resp.done:
+	case <-gone:
+	}
+
+	resp.mu.Lock()
+	resp.closed = true
+	hooks := resp.onEnd
+	resp.mu.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	resp.runner(func() error {
+		for _, hook := range hooks {
+			if err := hook(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// onEnded registers a hook called via runner when the response is completed or the client is gone.
+// The hooks are called by wait, before the request handling is finished.
+func (resp *response) onEnded(hook func() error) {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	resp.onEnd = append(resp.onEnd, hook)
+}
+
+// complete sends the buffered header (if not yet sent) and releases the waiting handler.
+// The caller must hold the lock.
+func (resp *response) complete() {
+	if resp.closed || resp.ended {
+		return
+	}
+
+	resp.writeHeader()
+
+	resp.ended = true
+
+	close(resp.done)
+}
+
+// write sends the whole response body and ends the response. Range requests are served with partial content.
+func (resp *response) write(data []byte) {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if resp.closed {
+		return
+	}
+
+	if !resp.ranged() {
+		_, err := resp.Write(data)
+
+		must(resp.runtime, err)
+	} else {
+		var modtime time.Time
+
+		if lastModified := resp.Header().Get("Last-Modified"); len(lastModified) != 0 {
+			modtime, _ = http.ParseTime(lastModified)
+		}
+
+		http.ServeContent(resp, resp.request, "", modtime, bytes.NewReader(data))
+	}
+
+	resp.complete()
+}
+
+// writeChunk sends a chunk of a streaming response and flushes it to the client.
+// The response is kept open until end is called.
+func (resp *response) writeChunk(chunk goja.Value) *goja.Object {
+	must(resp.runtime, resp.stream(toBytes(resp.runtime, chunk)))
+
+	return resp.this
+}
+
+// stream writes and flushes a chunk of a streaming response. Chunks are dropped silently once the client is gone.
+func (resp *response) stream(data []byte) error {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if resp.ended {
+		return errWriteAfterEnd
+	}
+
+	resp.streaming = true
+
+	if resp.closed {
+		return nil
+	}
+
+	if _, err := resp.Write(data); err != nil {
+		return err
+	}
+
+	resp.flushWriter()
+
+	return nil
+}
+
+// end completes the response, optionally sending a last chunk.
+func (resp *response) end(chunk goja.Value) *goja.Object {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if isSet(chunk) && !resp.closed && !resp.ended {
+		_, err := resp.Write(toBytes(resp.runtime, chunk))
+
+		must(resp.runtime, err)
+	}
+
+	resp.complete()
+
+	return resp.this
+}
+
+// flush sends the buffered header and body to the client.
+// The response is kept open until end is called.
+func (resp *response) flush() *goja.Object {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if !resp.ended {
+		resp.streaming = true
+	}
+
+	if !resp.closed {
+		resp.writeHeader()
+		resp.flushWriter()
+	}
+
+	return resp.this
+}
+
+// gone returns true if the response is ended or the client is gone.
+func (resp *response) gone() bool {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	return resp.ended || resp.closed
+}
+
+func (resp *response) flushWriter() {
+	if flusher, ok := resp.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (resp *response) ranged() bool {
+	if resp.request == nil || len(resp.request.Header.Get("Range")) == 0 {
+		return false
+	}
+
+	if resp.code != 0 && resp.code != http.StatusOK {
+		return false
+	}
+
+	return resp.request.Method == http.MethodGet || resp.request.Method == http.MethodHead
+}
+
+func (resp *response) json(v interface{}) *goja.Object {
 	resp.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	b, err := json.Marshal(v)
 
 	must(resp.runtime, err)
 
-	_, err = resp.Write(b)
+	resp.write(b)
 
-	must(resp.runtime, err)
+	return resp.this
 }
 
-func (resp *response) textf(format string, v ...interface{}) {
+// sendJSON sends a JSON response. Iterable iterators (like generator objects) are streamed as JSON array instead of marshaling the whole value.
+func (resp *response) sendJSON(value goja.Value) *goja.Object {
+	if isIterator(resp.runtime, value) {
+		return resp.jsonArray(value)
+	}
+
+	return resp.json(value.Export())
+}
+
+func (resp *response) textf(format string, v ...interface{}) *goja.Object {
 	resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
 
-	_, err := resp.Write([]byte(fmt.Sprintf(format, v...)))
+	resp.write([]byte(fmt.Sprintf(format, v...)))
 
-	must(resp.runtime, err)
+	return resp.this
 }
 
-func (resp *response) html(b []byte) {
+func (resp *response) html(b []byte) *goja.Object {
 	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	_, err := resp.Write(b)
+	resp.write(b)
 
-	must(resp.runtime, err)
+	return resp.this
 }
 
-func (resp *response) binary(b []byte) {
+// binary sends binary data, an ArrayBuffer, typed array or DataView is sent without copying.
+func (resp *response) binary(data goja.Value) *goja.Object {
 	resp.Header().Set("Content-Type", "application/octet-stream")
 
-	_, err := resp.Write(b)
+	resp.write(toBytes(resp.runtime, data))
 
-	must(resp.runtime, err)
+	return resp.this
 }
 
-func (resp *response) send(data interface{}) {
-	switch val := data.(type) {
+// send sends a string as HTML, binary data (ArrayBuffer, typed array, DataView) as octet-stream and anything else as JSON,
+// or as XML if the Accept request header field prefers XML.
+func (resp *response) send(data goja.Value) *goja.Object {
+	switch data.Export().(type) {
 	case string:
-		resp.html([]byte(val))
-	case []byte:
-		resp.binary(val)
-	default:
-		resp.json(data)
+		return resp.html([]byte(data.String()))
+	case goja.ArrayBuffer, []byte:
+		return resp.binary(data)
 	}
+
+	if _, isView := viewBytes(resp.runtime, data); isView {
+		return resp.binary(data)
+	}
+
+	resp.addVary("Accept")
+
+	if resp.request != nil && prefersXML(resp.request.Header.Get("Accept")) {
+		return resp.xml(data)
+	}
+
+	return resp.sendJSON(data)
 }
 
-func (resp *response) status(code int) {
-	resp.WriteHeader(code)
+// sendStatus sends the status code with its text as response body.
+func (resp *response) sendStatus(code int) *goja.Object {
+	resp.code = code
+
+	text := http.StatusText(code)
+	if len(text) == 0 {
+		text = strconv.Itoa(code)
+	}
+
+	return resp.textf("%s", text)
+}
+
+// jsonp sends a JSON response with JSONP support. The name of the callback query parameter
+// comes from the "jsonp callback name" setting (default callback).
+// Without callback parameter it is the same as json.
+func (resp *response) jsonp(v interface{}) *goja.Object {
+	callback := resp.jsonpCallback()
+	if len(callback) == 0 {
+		return resp.json(v)
+	}
+
+	b, err := json.Marshal(v)
+
+	must(resp.runtime, err)
+
+	resp.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+
+	resp.write([]byte("/**/ typeof " + callback + " === 'function' && " + callback + "(" + string(b) + ");"))
+
+	return resp.this
+}
+
+func (resp *response) jsonpCallback() string {
+	if resp.request == nil {
+		return ""
+	}
+
+	name, _ := resp.settings[settingJSONPCallbackName].(string)
+	if len(name) == 0 {
+		name = defaultJSONPCallbackName
+	}
+
+	return jsonpCallbackRE.ReplaceAllString(resp.request.URL.Query().Get(name), "")
 }
 
-func (resp *response) contentType(mime string) {
+// links appends the given links to the Link header field. The parameter maps relation types to URLs.
+func (resp *response) links(links *goja.Object) *goja.Object {
+	parts := make([]string, 0)
+
+	for _, rel := range links.Keys() {
+		parts = append(parts, "<"+links.Get(rel).String()+">; rel=\""+rel+"\"")
+	}
+
+	value := strings.Join(parts, ", ")
+
+	if current := resp.Header().Get("Link"); len(current) != 0 {
+		value = current + ", " + value
+	}
+
+	resp.Header().Set("Link", value)
+
+	return resp.this
+}
+
+// location sets the Location header field. The special value "back" refers to the Referer (default "/").
+func (resp *response) location(loc string) *goja.Object {
+	resp.Header().Set("Location", resp.resolveLocation(loc))
+
+	return resp.this
+}
+
+func (resp *response) resolveLocation(loc string) string {
+	if loc == "back" {
+		loc = "/"
+
+		if resp.request != nil {
+			if referer := resp.request.Referer(); len(referer) != 0 {
+				loc = referer
+			}
+		}
+	}
+
+	return encodeURL(loc)
+}
+
+// encodeURL percent-encodes characters not allowed in URLs, already encoded sequences are kept.
+func encodeURL(loc string) string {
+	var buff strings.Builder
+
+	for idx := 0; idx < len(loc); idx++ {
+		char := loc[idx]
+
+		switch {
+		case char == '%' && idx+2 < len(loc) && isHex(loc[idx+1]) && isHex(loc[idx+2]):
+			buff.WriteByte(char)
+		case char < 0x80 && char > 0x20 && strings.IndexByte(`"%<>\^`+"`{|}", char) < 0 && char != 0x7f:
+			buff.WriteByte(char)
+		default:
+			fmt.Fprintf(&buff, "%%%02X", char)
+		}
+	}
+
+	return buff.String()
+}
+
+func isHex(char byte) bool {
+	return strings.IndexByte("0123456789abcdefABCDEF", char) >= 0
+}
+
+func (resp *response) status(code int) *goja.Object {
+	resp.code = code
+
+	return resp.this
+}
+
+func (resp *response) isHeadersSent() bool {
+	return resp.headersSent
+}
+
+func (resp *response) contentType(mime string) *goja.Object {
 	resp.Header().Set("Content-Type", mime)
+
+	return resp.this
+}
+
+// vary adds the field (or array of fields) to the Vary header field, if not already listed.
+func (resp *response) vary(field goja.Value) *goja.Object {
+	var fields []string
+
+	if _, isString := field.Export().(string); isString {
+		fields = []string{field.String()}
+	} else {
+		must(resp.runtime, resp.runtime.ExportTo(field, &fields))
+	}
+
+	resp.addVary(fields...)
+
+	return resp.this
 }
 
-func (resp *response) vary(header string) {
-	resp.Header().Set("Vary", header)
+// addVary merges fields into the Vary header field the same way as the vary package used by Express.js does.
+// Built-in features that select content based on request header fields should call it.
+func (resp *response) addVary(fields ...string) {
+	resp.Header().Set("Vary", mergeVary(strings.Join(resp.Header().Values("Vary"), ", "), fields...))
 }
 
-func (resp *response) set(field, value string) {
+func mergeVary(header string, fields ...string) string {
+	parsed := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		parsed = append(parsed, parseVary(field)...)
+	}
+
+	current := parseVary(header)
+
+	for _, field := range append(current, parsed...) {
+		if field == "*" {
+			return "*"
+		}
+	}
+
+	for _, field := range parsed {
+		found := false
+
+		for _, existing := range current {
+			if strings.EqualFold(existing, field) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			current = append(current, field)
+		}
+	}
+
+	return strings.Join(current, ", ")
+}
+
+func parseVary(header string) []string {
+	fields := make([]string, 0)
+
+	for _, field := range strings.Split(header, ",") {
+		if field = strings.TrimSpace(field); len(field) != 0 {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func (resp *response) set(field, value string) *goja.Object {
 	resp.Header().Set(field, value)
+
+	return resp.this
 }
 
-func (resp *response) append(field string, value string) {
+func (resp *response) append(field string, value string) *goja.Object {
 	resp.Header().Add(field, value)
+
+	return resp.this
 }
 
-func (resp *response) redirect(code int, loc string) {
-	resp.WriteHeader(code)
-	resp.Header().Set("Location", loc)
+func (resp *response) getHeader(field string) goja.Value {
+	values := resp.Header().Values(field)
+
+	switch len(values) {
+	case 0:
+		return goja.Undefined()
+	case 1:
+		return resp.runtime.ToValue(values[0])
+	default:
+		return resp.runtime.ToValue(values)
+	}
+}
+
+func (resp *response) removeHeader(field string) *goja.Object {
+	resp.Header().Del(field)
+
+	return resp.this
+}
+
+// redirect accepts (loc) or (code, loc) arguments, the default status code is 302 (Found).
+func (resp *response) redirect(first goja.Value, second goja.Value) *goja.Object {
+	code, loc := http.StatusFound, first.String()
+
+	if isSet(second) {
+		code, loc = int(first.ToInteger()), second.String()
+	}
+
+	resp.location(loc)
+	resp.code = code
+
+	return resp.end(goja.Undefined())
+}
+
+func (resp *response) cookie(name string, value goja.Value, options goja.Value) *goja.Object {
+	opts := newCookieOptions(name, cookieValue(resp.runtime, value), options)
+
+	if opts.signed {
+		if len(resp.secrets) == 0 {
+			throwf(resp.runtime, "secret required for signed cookies")
+		}
+
+		opts.Value = signedCookiePrefix + signCookie(opts.Value, resp.secrets[0])
+	}
+
+	opts.Value = encodeCookieValue(opts.Value)
+
+	http.SetCookie(resp, opts.Cookie)
+
+	return resp.this
+}
+
+func (resp *response) clearCookie(name string, options goja.Value) *goja.Object {
+	opts := newCookieOptions(name, "", options)
+
+	opts.Expires = time.Unix(1, 0)
+	opts.MaxAge = -1
+
+	http.SetCookie(resp, opts.Cookie)
+
+	return resp.this
 }
@@ -5,11 +5,14 @@
 package muxpress
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/stretchr/testify/assert"
@@ -20,7 +23,7 @@ func Test_response_json(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -45,7 +48,7 @@ func Test_response_text(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -64,7 +67,7 @@ func Test_response_html(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -83,7 +86,7 @@ func Test_response_binary(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -99,12 +102,55 @@ func Test_response_binary(t *testing.T) {
 	assert.Equal(t, data, got)
 }
 
+func Test_response_binary_range(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=1-2")
+	res := newResponse(runtime, rec, req)
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "binary", value([]byte{1, 2, 3, 4, 5}))
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "application/octet-stream", rec.Header().Get("content-type"))
+	assert.Equal(t, "bytes 1-2/5", rec.Header().Get("content-range"))
+	assert.Equal(t, []byte{2, 3}, rec.Body.Bytes())
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=0-0,3-4")
+	res = newResponse(runtime, rec, req)
+	obj = wrapResponse(runtime, res)
+
+	callMethod(t, obj, "send", value("Hello, World!"))
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("content-type"), "multipart/byteranges; boundary="))
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=1-2")
+	req.Header.Set("If-Range", `"foo"`)
+	res = newResponse(runtime, rec, req)
+	obj = wrapResponse(runtime, res)
+
+	callMethod(t, obj, "set", value("ETag"), value(`"bar"`))
+	callMethod(t, obj, "binary", value([]byte{1, 2, 3, 4, 5}))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5}, rec.Body.Bytes())
+}
+
 func Test_response_send(t *testing.T) {
 	t.Parallel()
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -113,7 +159,7 @@ func Test_response_send(t *testing.T) {
 	assert.Equal(t, "application/octet-stream", rec.Header().Get("content-type"))
 
 	rec = httptest.NewRecorder()
-	res = newResponse(runtime, rec)
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj = wrapResponse(runtime, res)
 
 	callMethod(t, obj, "send", value("<html></html>"))
@@ -121,7 +167,7 @@ func Test_response_send(t *testing.T) {
 	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("content-type"))
 
 	rec = httptest.NewRecorder()
-	res = newResponse(runtime, rec)
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj = wrapResponse(runtime, res)
 
 	callMethod(t, obj, "send", value(map[string]string{"foo": "bar"}))
@@ -134,7 +180,7 @@ func Test_response_contentType(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -148,13 +194,40 @@ func Test_response_vary(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
 	assert.Empty(t, rec.Header().Get("vary"))
 	callMethod(t, obj, "vary", value("user-agent"))
 	assert.Equal(t, "user-agent", rec.Header().Get("vary"))
+
+	callMethod(t, obj, "vary", value("Accept-Encoding, User-Agent"))
+	assert.Equal(t, "user-agent, Accept-Encoding", rec.Header().Get("vary"))
+
+	callMethod(t, obj, "vary", value([]string{"Origin", "accept-encoding"}))
+	assert.Equal(t, "user-agent, Accept-Encoding, Origin", rec.Header().Get("vary"))
+
+	rec.Header().Add("Vary", "Cookie")
+	res.addVary("Accept")
+	assert.Equal(t, []string{"user-agent, Accept-Encoding, Origin, Cookie, Accept"}, rec.Header().Values("vary"))
+
+	callMethod(t, obj, "vary", value("*"))
+	assert.Equal(t, "*", rec.Header().Get("vary"))
+
+	callMethod(t, obj, "vary", value("Accept"))
+	assert.Equal(t, "*", rec.Header().Get("vary"))
+}
+
+func Test_mergeVary(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Accept", mergeVary("", "Accept"))
+	assert.Equal(t, "Accept", mergeVary("Accept", "accept", " ACCEPT "))
+	assert.Equal(t, "Accept, Origin", mergeVary("Accept,", "Origin,"))
+	assert.Equal(t, "*", mergeVary("*", "Accept"))
+	assert.Equal(t, "*", mergeVary("Accept", "Origin, *"))
+	assert.Equal(t, "", mergeVary(""))
 }
 
 func Test_response_redirect(t *testing.T) {
@@ -162,14 +235,138 @@ func Test_response_redirect(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
 	assert.Empty(t, rec.Header().Get("location"))
 	callMethod(t, obj, "redirect", value(http.StatusPermanentRedirect), value("http://example.com"))
+	<-res.done
 	assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
 	assert.Equal(t, "http://example.com", rec.Header().Get("location"))
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj = wrapResponse(runtime, res)
+
+	callMethod(t, obj, "redirect", value("/foo"))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/foo", rec.Result().Header.Get("location"))
+}
+
+func Test_response_location(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	res := newResponse(runtime, rec, req)
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "location", value("back"))
+	assert.Equal(t, "/", rec.Header().Get("Location"))
+
+	req.Header.Set("Referer", "http://example.com/from")
+
+	callMethod(t, obj, "location", value("back"))
+	assert.Equal(t, "http://example.com/from", rec.Header().Get("Location"))
+
+	callMethod(t, obj, "location", value("/foo bar?q=ü&x=%20"))
+	assert.Equal(t, "/foo%20bar?q=%C3%BC&x=%20", rec.Header().Get("Location"))
+
+	callMethod(t, obj, "redirect", value("back"))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "http://example.com/from", rec.Header().Get("Location"))
+}
+
+func Test_response_sendStatus(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "sendStatus", value(http.StatusForbidden))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Forbidden", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	res.sendStatus(599)
+
+	assert.Equal(t, 599, rec.Code)
+	assert.Equal(t, "599", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	res.sendStatus(http.StatusNoContent)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func Test_response_jsonp(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/?callback=foo.bar<script>", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "jsonp", value(map[string]interface{}{"foo": "bar"}))
+
+	assert.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, `/**/ typeof foo.barscript === 'function' && foo.barscript({"foo":"bar"});`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/?cb=foo", nil))
+	res.settings = map[string]interface{}{settingJSONPCallbackName: "cb"}
+
+	res.jsonp([]int{1})
+
+	assert.Equal(t, `/**/ typeof foo === 'function' && foo([1]);`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	res.jsonp([]int{1})
+
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `[1]`, rec.Body.String())
+}
+
+func Test_response_links(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+
+	links, err := runtime.RunString(`({next: "/users?page=2", last: "/users?page=5"})`)
+
+	assert.NoError(t, err)
+
+	callMethod(t, obj, "links", links)
+
+	assert.Equal(t, `</users?page=2>; rel="next", </users?page=5>; rel="last"`, rec.Header().Get("Link"))
+
+	links, err = runtime.RunString(`({prev: "/users?page=1"})`)
+
+	assert.NoError(t, err)
+
+	callMethod(t, obj, "links", links)
+
+	assert.Equal(t, `</users?page=2>; rel="next", </users?page=5>; rel="last", </users?page=1>; rel="prev"`, rec.Header().Get("Link"))
 }
 
 func Test_response_set(t *testing.T) {
@@ -177,12 +374,12 @@ func Test_response_set(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
 	assert.Empty(t, rec.Header().Get("foo"))
-	callMethod(t, obj, "set", value("foo"), value("bar"))
+	assert.Same(t, obj, callMethod(t, obj, "set", value("foo"), value("bar")))
 	assert.Equal(t, "bar", rec.Header().Get("foo"))
 	assert.Equal(t, []string{"bar"}, rec.Header().Values("foo"))
 }
@@ -192,7 +389,7 @@ func Test_response_append(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
@@ -209,13 +406,227 @@ func Test_response_status(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	res := newResponse(runtime, rec)
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	obj := wrapResponse(runtime, res)
 	value := runtime.ToValue
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 	callMethod(t, obj, "status", value(http.StatusBadRequest))
+	callMethod(t, obj, "set", value("foo"), value("bar"))
+	assert.False(t, obj.Get("headersSent").ToBoolean())
+	callMethod(t, obj, "text", value("Bad Request"))
+	assert.True(t, obj.Get("headersSent").ToBoolean())
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "bar", rec.Result().Header.Get("foo"))
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj = wrapResponse(runtime, res)
+
+	callMethod(t, obj, "status", value(http.StatusCreated))
+	assert.False(t, res.headersSent)
+	callMethod(t, obj, "end")
+	assert.True(t, res.headersSent)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func Test_response_getHeader(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	assert.True(t, goja.IsUndefined(callMethod(t, obj, "getHeader", value("foo"))))
+	callMethod(t, obj, "set", value("foo"), value("bar"))
+	assert.Equal(t, "bar", callMethod(t, obj, "getHeader", value("Foo")).String())
+	callMethod(t, obj, "append", value("foo"), value("dummy"))
+	assert.Equal(t, []string{"bar", "dummy"}, callMethod(t, obj, "getHeader", value("foo")).Export())
+	callMethod(t, obj, "removeHeader", value("foo"))
+	assert.True(t, goja.IsUndefined(callMethod(t, obj, "getHeader", value("foo"))))
+}
+
+func Test_response_cookie(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	options := runtime.NewObject()
+
+	assert.NoError(t, options.Set("httpOnly", true))
+
+	callMethod(t, obj, "cookie", value("foo"), value("bar"), options)
+	callMethod(t, obj, "cookie", value("obj"), value(map[string]interface{}{"answer": 42}))
+
+	cookies := rec.Result().Cookies()
+
+	assert.Len(t, cookies, 2)
+	assert.Equal(t, "foo", cookies[0].Name)
+	assert.Equal(t, "bar", cookies[0].Value)
+	assert.Equal(t, "/", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, "j%3A%7B%22answer%22%3A42%7D", cookies[1].Value)
+
+	signed := runtime.NewObject()
+
+	assert.NoError(t, signed.Set("signed", true))
+
+	call, _ := goja.AssertFunction(obj.Get("cookie"))
+	_, err := call(obj, value("foo"), value("bar"), signed)
+
+	assert.Error(t, err)
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	res.secrets = []string{"secret", "other"}
+	obj = wrapResponse(runtime, res)
+
+	callMethod(t, obj, "cookie", value("foo"), value("bar"), signed)
+
+	cookies = rec.Result().Cookies()
+
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, encodeCookieValue("s:"+signCookie("bar", "secret")), cookies[0].Value)
+}
+
+func Test_response_clearCookie(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "clearCookie", value("foo"))
+
+	cookies := rec.Result().Cookies()
+
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "foo", cookies[0].Name)
+	assert.Empty(t, cookies[0].Value)
+	assert.Equal(t, "/", cookies[0].Path)
+	assert.True(t, cookies[0].Expires.Before(time.Now()))
+}
+
+func Test_response_write(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "status", value(http.StatusAccepted))
+	callMethod(t, obj, "write", value("Hello, "))
+
+	assert.True(t, res.streaming)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	select {
+	case <-res.done:
+		assert.Fail(t, "streaming response should be kept open")
+	default:
+	}
+
+	callMethod(t, obj, "write", value([]byte("World")))
+	callMethod(t, obj, "end", value("!"))
+
+	<-res.done
+
+	assert.Equal(t, "Hello, World!", rec.Body.String())
+
+	call, _ := goja.AssertFunction(obj.Get("write"))
+	_, err := call(obj, value("after end"))
+
+	assert.Error(t, err)
+}
+
+func Test_response_end(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "status", value(http.StatusNoContent))
+	callMethod(t, obj, "end")
+	callMethod(t, obj, "end")
+
+	<-res.done
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, res.streaming)
+}
+
+func Test_response_flush(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "set", value("X-Foo"), value("bar"))
+	callMethod(t, obj, "flush")
+
+	assert.True(t, rec.Flushed)
+	assert.True(t, res.headersSent)
+	assert.Equal(t, "bar", rec.Result().Header.Get("X-Foo"))
+
+	callMethod(t, obj, "json", value(map[string]interface{}{"foo": "bar"}))
+
+	<-res.done
+
+	assert.Equal(t, `{"foo":"bar"}`, rec.Body.String())
+}
+
+func Test_response_wait(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.TODO())
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "write", value("Hello"))
+
+	cancel()
+	res.wait()
+
+	assert.True(t, res.closed)
+
+	callMethod(t, obj, "write", value("ignored"))
+	callMethod(t, obj, "end", value("ignored"))
+
+	assert.Equal(t, "Hello", rec.Body.String())
+}
+
+func Test_response_locals(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+
+	locals, isObject := obj.Get("locals").(*goja.Object)
+
+	assert.True(t, isObject, "locals must be object")
+	assert.Empty(t, locals.Keys())
 }
 
 func callMethod(t *testing.T, this *goja.Object, name string, args ...goja.Value) goja.Value {
@@ -242,9 +653,10 @@ func Test_wrap_responseWriter(t *testing.T) {
 
 	runtime := goja.New()
 	rec := httptest.NewRecorder()
-	obj := wrapResponseWriter(runtime, rec)
+	obj := wrapResponseWriter(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
 	value := runtime.ToValue
 
 	callMethod(t, obj, "status", value(http.StatusMovedPermanently))
+	callMethod(t, obj, "send", value("moved"))
 	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
 }
@@ -7,9 +7,12 @@ package muxpress
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
 	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 )
 
@@ -45,17 +48,51 @@ func (chain middlewareChain) call(req *goja.Object, res *goja.Object, cascade ..
 type router struct {
 	*httprouter.Router
 	runner RunnerFunc
+	logger logrus.FieldLogger
 
 	middlewares middlewareChain
 	filesystem  afero.Fs
+	secrets     []string
+	views       *views
+	settings    map[string]interface{}
+	getRoutes   map[string]*getRoute
+}
+
+// getRoute dispatches the GET requests of a path, so a WebSocket endpoint and an HTTP handler can share the path.
+// Upgrade requests go to the WebSocket endpoint, other requests to the HTTP handler (if any).
+type getRoute struct {
+	path      string
+	http      http.HandlerFunc
+	webSocket http.HandlerFunc
+}
+
+func (route *getRoute) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if route.webSocket != nil && (route.http == nil || websocket.IsWebSocketUpgrade(request)) {
+		route.webSocket(writer, request)
+
+		return
+	}
+
+	route.http(writer, request)
+}
+
+// set sets the handler, panics like httprouter if the handler is already registered.
+func (route *getRoute) set(handler *http.HandlerFunc, fn http.HandlerFunc) {
+	if *handler != nil {
+		panic("a handle is already registered for path '" + route.path + "'")
+	}
+
+	*handler = fn
 }
 
 func newRouter(runner RunnerFunc, filesystem afero.Fs) *router {
 	return &router{
 		Router:      httprouter.New(),
 		runner:      runner,
+		logger:      logrus.StandardLogger(),
 		filesystem:  filesystem,
 		middlewares: make(middlewareChain, 0),
+		getRoutes:   make(map[string]*getRoute),
 	}
 }
 
@@ -77,22 +114,139 @@ func (r *router) runSync(fn func() error) {
 	<-done
 }
 
-func (r *router) handle(runtime *goja.Runtime, response http.ResponseWriter, request *http.Request, middlewares ...middleware) {
+func (r *router) handle(runtime *goja.Runtime, writer http.ResponseWriter, request *http.Request, middlewares ...middleware) {
+	res := newResponse(runtime, writer, request)
+	res.secrets = r.secrets
+	res.runner = r.runner
+	res.logger = r.logger
+	res.filesystem = r.filesystem
+	res.views = r.views
+	res.settings = r.settings
+
 	r.runSync(func() error {
-		req := wrapRequest(runtime, request)
-		res := wrapResponseWriter(runtime, response)
-		r.middlewares.call(req, res, middlewares...)
+		req := newRequest(runtime, request)
+		req.secrets = r.secrets
+
+		r.middlewares.call(wrapRequest(runtime, req), wrapResponse(runtime, res), middlewares...)
 
 		return nil
 	})
+
+	res.wait()
 }
 
 func (r *router) handleMethod(runtime *goja.Runtime, method string, path string, middlewares ...middleware) {
-	r.Router.HandlerFunc(method, path, func(response http.ResponseWriter, request *http.Request) {
+	handler := func(response http.ResponseWriter, request *http.Request) {
 		r.handle(runtime, response, request, middlewares...)
+	}
+
+	if method != http.MethodGet {
+		r.Router.HandlerFunc(method, path, handler)
+
+		return
+	}
+
+	route := r.getRoute(path)
+
+	route.set(&route.http, handler)
+}
+
+// getRoute returns the GET route of the path, registering it on first use.
+func (r *router) getRoute(path string) *getRoute {
+	route, found := r.getRoutes[path]
+	if !found {
+		route = &getRoute{path: path} //nolint:exhaustruct
+
+		r.Router.Handler(http.MethodGet, path, route)
+		r.getRoutes[path] = route
+	}
+
+	return route
+}
+
+// handleWebSocket registers a WebSocket endpoint. Upgrade requests run through the middleware chain first,
+// the connection is upgraded only if every middleware called next without sending a response.
+// The path may have an HTTP GET handler too, it handles the requests which are not upgrade requests.
+func (r *router) handleWebSocket(runtime *goja.Runtime, path string, handler goja.Callable, middlewares ...middleware) {
+	route := r.getRoute(path)
+
+	route.set(&route.webSocket, func(writer http.ResponseWriter, request *http.Request) {
+		r.upgrade(runtime, writer, request, handler, middlewares...)
 	})
 }
 
+func (r *router) upgrade(
+	runtime *goja.Runtime,
+	writer http.ResponseWriter,
+	request *http.Request,
+	handler goja.Callable,
+	middlewares ...middleware,
+) {
+	res := newResponse(runtime, writer, request)
+	res.secrets = r.secrets
+	res.runner = r.runner
+	res.logger = r.logger
+	res.filesystem = r.filesystem
+	res.views = r.views
+	res.settings = r.settings
+
+	var (
+		req      *goja.Object
+		accepted bool
+		failed   bool
+	)
+
+	accept := func(_ *goja.Object, _ *goja.Object, _ goja.Callable) {
+		accepted = !res.headersSent && !res.streaming
+	}
+
+	r.runSync(func() error {
+		wreq := newRequest(runtime, request)
+		wreq.secrets = r.secrets
+
+		req = wrapRequest(runtime, wreq)
+
+		cascade := make([]middleware, 0, len(middlewares)+1)
+		cascade = append(cascade, middlewares...)
+
+		r.middlewares.call(req, wrapResponse(runtime, res), append(cascade, accept)...)
+
+		return nil
+	})
+
+	if !accepted {
+		res.wait()
+
+		return
+	}
+
+	conn, err := upgrader.Upgrade(writer, request, writer.Header())
+	if err != nil {
+		return // upgrader already replied with an HTTP error
+	}
+
+	socket := newWebSocket(runtime, conn, r.runner)
+
+	r.runSync(func() error {
+		if _, err := handler(goja.Undefined(), wrapWebSocket(runtime, socket), req); err != nil {
+			msg := websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "")
+
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsControlTimeout)) //nolint:errcheck
+			conn.Close()
+
+			failed = true
+
+			return err
+		}
+
+		return nil
+	})
+
+	if !failed {
+		socket.serve()
+	}
+}
+
 func (r *router) fixpath(path string) string {
 	if strings.HasSuffix(path, "/*filepath") {
 		return path
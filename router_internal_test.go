@@ -250,3 +250,62 @@ func Test_router_use(t *testing.T) {
 	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("content-type"))
 	assert.Equal(t, "42", rec.Header().Get("magic"))
 }
+
+func Test_router_handle_deferred(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	router := newRouter(syncRunner(), nil)
+
+	var deferred middleware = func(req *goja.Object, res *goja.Object, next goja.Callable) {
+		time.AfterFunc(10*time.Millisecond, func() {
+			router.runner(func() error {
+				callMethod(t, res, "status", runtime.ToValue(http.StatusAccepted))
+				callMethod(t, res, "write", runtime.ToValue("Hello, "))
+				callMethod(t, res, "end", runtime.ToValue("World!"))
+
+				return nil
+			})
+		})
+	}
+
+	router.handleMethod(runtime, http.MethodGet, "/deferred", deferred)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/deferred", nil)
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "Hello, World!", rec.Body.String())
+}
+
+func Test_router_handle_streaming(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	router := newRouter(syncRunner(), nil)
+
+	var stream middleware = func(req *goja.Object, res *goja.Object, next goja.Callable) {
+		callMethod(t, res, "write", runtime.ToValue("Hello, "))
+
+		time.AfterFunc(10*time.Millisecond, func() {
+			router.runner(func() error {
+				callMethod(t, res, "end", runtime.ToValue("World!"))
+
+				return nil
+			})
+		})
+	}
+
+	router.handleMethod(runtime, http.MethodGet, "/stream", stream)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "Hello, World!", rec.Body.String())
+}
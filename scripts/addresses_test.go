@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestAddresses(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.json({ protocol: req.protocol })
+})
+
+app.listen(0, '127.0.0.1')
+app.listen({ host: '127.0.0.1', tls: { selfSigned: true } })
+
+test('addresses', () => {
+	assert.Equal(2, app.addresses.length)
+	assert.Equal(app.host, app.addresses[0].host)
+	assert.Equal(app.port, app.addresses[0].port)
+	assert.Equal('http', app.addresses[0].protocol)
+	assert.Equal('https', app.addresses[1].protocol)
+})
+
+test('all addresses are served', () => {
+	for (const addr of app.addresses) {
+		const resp = client.Clone().SetRootCertFromString(app.ca).R().Get(addr.protocol + '://' + addr.host)
+		assert.Equal({ protocol: addr.protocol }, JSON.parse(resp.ToString()))
+	}
+})
+
+test('shutdown stops all', () => {
+	const host = app.host
+	app.shutdown()
+	assert.Equal(2, app.addresses.length, 'the last addresses are kept')
+	assert.Equal(host, app.host)
+	for (const addr of app.addresses) {
+		let refused = false
+		try {
+			client.Clone().SetRootCertFromString(app.ca).R().Get(addr.protocol + '://' + addr.host)
+		} catch (e) {
+			refused = true
+		}
+		assert.True(refused)
+	}
+})
+
+test('listen after shutdown', () => {
+	app.listen(0, '127.0.0.1')
+	assert.Equal(1, app.addresses.length)
+	app.shutdown()
+})
+
+// !js
+`)
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+const payload = new Uint8Array(64 * 1024).map((_, i) => i % 256)
+
+app.get('/payload', (req, res) => {
+	res.binary(payload.subarray(0, 1024))
+})
+
+app.get('/view', (req, res) => {
+	res.send(new DataView(payload.buffer, 1, 3))
+})
+
+app.get('/buffer', (req, res) => {
+	res.send(payload.buffer)
+})
+
+app.post('/echo', (req, res) => {
+	res.json({ isBuffer: req.body instanceof ArrayBuffer, length: req.body.byteLength, last: new Uint8Array(req.body)[2] })
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('typed array', () => {
+	const resp = client.R().Get('/payload')
+	assert.Equal('application/octet-stream', resp.GetHeader('Content-Type'))
+	assert.Equal(1024, resp.Bytes().length)
+})
+
+test('DataView', () => {
+	const resp = client.R().Get('/view')
+	assert.Equal('application/octet-stream', resp.GetHeader('Content-Type'))
+	assert.Equal([1, 2, 3], Array.from(resp.Bytes()))
+})
+
+test('ArrayBuffer', () => {
+	const resp = client.R().Get('/buffer')
+	assert.Equal(64 * 1024, resp.Bytes().length)
+})
+
+test('body', () => {
+	const resp = client.R().SetHeader('Content-Type', 'application/octet-stream').SetBodyString('abc').Post('/echo')
+	assert.Equal({ isBuffer: true, length: 3, last: 99 }, JSON.parse(resp.ToString()))
+})
+
+// !js
+`)
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"testing"
+
+	"github.com/szkiba/muxpress"
+)
+
+func TestCookie(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/login', (req, res) => {
+	res.cookie('user', 'joe', { signed: true, httpOnly: true })
+	res.cookie('prefs', { theme: 'dark' })
+	res.text('ok')
+})
+
+app.get('/logout', (req, res) => {
+	res.clearCookie('user')
+	res.text('ok')
+})
+
+app.get('/whoami', (req, res) => {
+	res.json({ user: req.signedCookies.user, prefs: req.cookies.prefs })
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host).SetCookieJar(null)
+})
+
+test('login', () => {
+	const resp = client.R().Get('/login')
+	assert.Equal(200, resp.GetStatusCode())
+
+	const cookies = resp.Cookies()
+	assert.Equal(2, cookies.length)
+
+	const data = JSON.parse(client.R().SetCookies(...cookies).Get('/whoami').ToString())
+	assert.Equal('joe', data.user)
+	assert.Equal('dark', data.prefs.theme)
+})
+
+test('tampered', () => {
+	const data = JSON.parse(client.R().SetCookies({ Name: 'user', Value: 's:joe.invalid' }).Get('/whoami').ToString())
+	assert.Equal(false, data.user)
+})
+
+test('logout', () => {
+	const resp = client.R().Get('/logout')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('user', resp.Cookies()[0].Name)
+	assert.Equal(-1, resp.Cookies()[0].MaxAge)
+})
+
+// !js
+`, muxpress.WithSecret("secret"))
+}
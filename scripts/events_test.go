@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+let listening = null
+let request = null
+
+app.get('/', (req, res) => {
+	res.text('ok')
+})
+
+app
+	.on('listening', (addr) => {
+		listening = addr
+	})
+	.on('request', (req) => {
+		request = req
+	})
+
+app.listen(0, '127.0.0.1', () => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('listening', () => {
+	assert.Equal(app.host, listening.host)
+	assert.Equal('http', listening.protocol)
+})
+
+test('request', () => {
+	assert.Equal(200, client.R().Get('/?foo=bar').StatusCode)
+	assert.Equal('GET', request.method)
+	assert.Equal('/?foo=bar', request.url)
+	assert.Equal('1.1', request.httpVersion)
+})
+
+test('unsupported', () => {
+	let thrown = false
+	try {
+		app.on('foo', () => {})
+	} catch (e) {
+		thrown = true
+	}
+	assert.True(thrown)
+})
+
+// !js
+`)
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/szkiba/muxpress"
+)
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	filesystem := afero.NewMemMapFs()
+
+	assert.NoError(t, afero.WriteFile(filesystem, "/files/report.csv", []byte("a,b\n1,2\n"), 0o644))
+	assert.NoError(t, afero.WriteFile(filesystem, "/secret.txt", []byte("secret"), 0o644))
+
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/files/:name', (req, res) => {
+	res.sendFile(req.params.name, { root: '/files', maxAge: 3600000 })
+})
+
+app.get('/file', (req, res) => {
+	res.sendFile(req.query.name, { root: '/files' })
+})
+
+app.get('/download', (req, res) => {
+	res.download('/files/report.csv', 'monthly.csv')
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('sendFile', () => {
+	const resp = client.R().Get('/files/report.csv')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('text/csv; charset=utf-8', resp.GetHeader('Content-Type'))
+	assert.Equal('public, max-age=3600', resp.GetHeader('Cache-Control'))
+	assert.Equal('a,b\n1,2\n', resp.ToString())
+})
+
+test('range', () => {
+	const resp = client.R().SetHeader('Range', 'bytes=0-2').Get('/files/report.csv')
+	assert.Equal(206, resp.GetStatusCode())
+	assert.Equal('a,b', resp.ToString())
+})
+
+test('traversal', () => {
+	const resp = client.R().Get('/file?name=../secret.txt')
+	assert.Equal(403, resp.GetStatusCode())
+})
+
+test('missing', () => {
+	const resp = client.R().Get('/files/missing.csv')
+	assert.Equal(404, resp.GetStatusCode())
+})
+
+test('download', () => {
+	const resp = client.R().Get('/download')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('attachment; filename=monthly.csv', resp.GetHeader('Content-Disposition'))
+	assert.Equal('a,b\n1,2\n', resp.ToString())
+})
+
+// !js
+`, muxpress.WithFS(filesystem))
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestHelpers(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.set('jsonp callback name', 'cb')
+
+app.get('/forbidden', (req, res) => {
+	res.sendStatus(403)
+})
+
+app.get('/jsonp', (req, res) => {
+	res.jsonp({ user: 'joe' })
+})
+
+app.get('/users', (req, res) => {
+	res.links({ next: '/users?page=2', last: '/users?page=5' }).json([])
+})
+
+app.get('/back', (req, res) => {
+	res.location('back').sendStatus(303)
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('sendStatus', () => {
+	const resp = client.R().Get('/forbidden')
+	assert.Equal(403, resp.GetStatusCode())
+	assert.Equal('Forbidden', resp.ToString())
+})
+
+test('jsonp', () => {
+	let resp = client.R().Get('/jsonp?cb=handle')
+	assert.Equal('text/javascript; charset=utf-8', resp.GetHeader('Content-Type'))
+	assert.Equal('/**/ typeof handle === \'function\' && handle({"user":"joe"});', resp.ToString())
+
+	resp = client.R().Get('/jsonp')
+	assert.Equal('application/json; charset=utf-8', resp.GetHeader('Content-Type'))
+})
+
+test('links', () => {
+	const resp = client.R().Get('/users')
+	assert.Equal('</users?page=2>; rel="next", </users?page=5>; rel="last"', resp.GetHeader('Link'))
+})
+
+test('location', () => {
+	const resp = client.R().SetHeader('Referer', '/from').Get('/back')
+	assert.Equal(303, resp.Response.Request.Response.StatusCode)
+	assert.Equal('/from', resp.Response.Request.Response.Header.Get('Location'))
+})
+
+// !js
+`)
+}
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestHTTP2(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.json({ httpVersion: req.httpVersion })
+})
+
+app.listen({ tls: { selfSigned: true }, http2: { maxConcurrentStreams: 250 } }, () => {
+	client.SetBaseURL('https://' + app.host).SetRootCertFromString(app.ca)
+})
+
+test('HTTP/2', () => {
+	const resp = client.Clone().EnableForceHTTP2().R().Get('/')
+	assert.Equal({ httpVersion: '2.0' }, JSON.parse(resp.ToString()))
+})
+
+test('HTTP/1.1', () => {
+	const resp = client.Clone().EnableForceHTTP1().R().Get('/')
+	assert.Equal({ httpVersion: '1.1' }, JSON.parse(resp.ToString()))
+})
+
+// !js
+`)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/szkiba/muxpress"
+)
+
+func TestHTTP3(t *testing.T) {
+	t.Parallel()
+
+	// The script holds the lock of the runner, like an event loop. It is released while waiting for the
+	// HTTP/3 response, because UDP traffic does not synchronize the request handler with the script.
+	var mu sync.Mutex
+
+	runner := func(fn func() error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.NoError(t, fn())
+	}
+
+	// The HTTP/3 transport of req ignores the configured root certificates, so the requests are sent by Go clients.
+	get := func(url string, caPEM string, useHTTP3 bool) map[string]string {
+		mu.Unlock()
+		defer mu.Lock()
+
+		pool := x509.NewCertPool()
+
+		assert.True(t, pool.AppendCertsFromPEM([]byte(caPEM)))
+
+		config := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12} //nolint:exhaustruct
+
+		var transport http.RoundTripper = &http.Transport{TLSClientConfig: config} //nolint:exhaustruct
+
+		if useHTTP3 {
+			h3 := &http3.Transport{TLSClientConfig: config} //nolint:exhaustruct
+
+			defer h3.Close()
+
+			transport = h3
+		}
+
+		resp, err := (&http.Client{Transport: transport}).Get(url) //nolint:exhaustruct,noctx
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+
+		assert.NoError(t, err)
+
+		return map[string]string{"body": string(body), "altSvc": resp.Header.Get("Alt-Svc")}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	jsWith(t, map[string]interface{}{"get": get}, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.json({ httpVersion: req.httpVersion })
+})
+
+app.listen({ tls: { selfSigned: true }, http3: true })
+
+test('HTTP/3', () => {
+	const resp = get('https://' + app.host + '/', app.ca, true)
+	assert.Equal({ httpVersion: '3.0' }, JSON.parse(resp.body))
+	assert.Equal('', resp.altSvc)
+})
+
+test('Alt-Svc', () => {
+	const resp = get('https://' + app.host + '/', app.ca, false)
+	assert.Equal('h3=":' + app.port + '"; ma=2592000', resp.altSvc)
+})
+
+// !js
+`, muxpress.WithRunner(runner))
+}
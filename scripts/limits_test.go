@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.text('ok')
+})
+
+app.listen({ host: '127.0.0.1', maxHeaderBytes: 256, readTimeout: '5s', idleTimeout: 1000 }, () => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('small headers', () => {
+	const resp = client.R().Get('/')
+	assert.Equal(200, resp.StatusCode)
+})
+
+test('too large headers', () => {
+	const resp = client.R().SetHeader('X-Large', 'x'.repeat(16384)).Get('/')
+	assert.Equal(431, resp.StatusCode)
+})
+
+test('invalid duration', () => {
+	assert.Panics(() => new Application().listen({ readTimeout: 'forever' }))
+})
+
+// !js
+`)
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestLocals(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.locals.greeting = "Hello"
+
+app.use((req, res, next) => {
+	res.locals.user = req.query.user
+	next()
+})
+
+app.get('/', (req, res) => {
+	res.text("%s, %s!", app.locals.greeting, res.locals.user)
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('locals', () => {
+	const resp = client.R().Get('/?user=Joe')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('Hello, Joe!', resp.ToString())
+})
+
+// !js
+`)
+}
@@ -12,7 +12,7 @@ func TestMethods(t *testing.T) {
 // js
 const app = new Application()
 
-const ping = (req, res)  => res.status(200)
+const ping = (req, res)  => res.status(200).end()
 
 app.get('/',ping)
 app.head('/',ping)
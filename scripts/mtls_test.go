@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/szkiba/muxpress"
+)
+
+func TestMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	ca, err := muxpress.NewCertificateAuthority()
+
+	assert.NoError(t, err)
+
+	// the authority of the application issues the server certificate, the partner certificate is self-signed
+	partnerPEM, partnerKey := newCertificate(t)
+
+	partner, err := tls.X509KeyPair(partnerPEM, partnerKey)
+
+	assert.NoError(t, err)
+
+	jsWith(t, map[string]interface{}{"partner": partner, "partnerCA": string(partnerPEM)}, `
+// js
+const app = new Application()
+
+app.use((req, res, next) => {
+	if (!req.client || req.client.commonName != 'localhost') {
+		res.status(403).text('Forbidden')
+		return
+	}
+	next()
+})
+
+app.get('/whoami', (req, res) => {
+	res.json({ subject: req.client.subject, ipAddresses: req.client.ipAddresses, fingerprint: req.client.fingerprint.length })
+})
+
+app.listen({ host: '127.0.0.1', tls: { selfSigned: true, ca: partnerCA, clientAuth: 'optional' } }, () => {
+	client.SetBaseURL('https://' + app.host).SetRootCertFromString(app.ca)
+})
+
+test('anonymous', () => {
+	assert.Equal(403, client.R().Get('/whoami').StatusCode)
+})
+
+test('client certificate', () => {
+	const resp = client.Clone().SetCerts(partner).R().Get('/whoami')
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal({ subject: 'CN=localhost', ipAddresses: ['127.0.0.1'], fingerprint: 95 }, JSON.parse(resp.ToString()))
+})
+
+// !js
+`, muxpress.WithCertificateAuthority(ca))
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNDJSON(t *testing.T) {
+	t.Parallel()
+
+	var host string
+
+	ready := func(h string) { host = h }
+
+	jsWith(t, map[string]interface{}{"ready": ready}, `
+// js
+const app = new Application()
+
+function* records(count) {
+	for (let id = 0; id < count; id++) {
+		yield { id, name: 'user' + id }
+	}
+}
+
+function delayed(count) {
+	let id = 0
+	return {
+		next: () => Promise.resolve(id < count ? { value: { id: id++ }, done: false } : { done: true })
+	}
+}
+
+app.get('/ndjson', (req, res) => {
+	res.ndjson(records(10000))
+})
+
+app.get('/array', (req, res) => {
+	res.jsonArray(records(10000))
+})
+
+app.get('/json', (req, res) => {
+	res.json(records(3))
+})
+
+app.get('/async', (req, res) => {
+	res.ndjson(delayed(3))
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+	ready(app.host)
+})
+
+test('ndjson', () => {
+	const resp = client.R().Get('/ndjson')
+	assert.Equal('application/x-ndjson', resp.GetHeader('Content-Type'))
+	const lines = resp.ToString().trim().split('\n')
+	assert.Equal(10000, lines.length)
+	assert.Equal({ id: 9999, name: 'user9999' }, JSON.parse(lines[9999]))
+})
+
+test('JSON array', () => {
+	const resp = client.R().Get('/array')
+	assert.Equal('application/json; charset=utf-8', resp.GetHeader('Content-Type'))
+	assert.Equal(10000, JSON.parse(resp.ToString()).length)
+})
+
+test('json with generator', () => {
+	assert.Equal([{ id: 0, name: 'user0' }, { id: 1, name: 'user1' }, { id: 2, name: 'user2' }], JSON.parse(client.R().Get('/json').ToString()))
+})
+
+// !js
+`)
+
+	// promises are resolved when the script is finished, so the async endpoint is called from Go
+	resp, err := http.Get("http://" + host + "/async") //nolint:noctx
+
+	assert.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":0}\n{\"id\":1}\n{\"id\":2}\n", string(body))
+}
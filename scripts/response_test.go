@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/szkiba/muxpress"
+)
+
+// chainArgs contains JavaScript arguments for calling chainable response methods.
+var chainArgs = map[string]string{
+	"append":       `"X-Append", "foo"`,
+	"attachment":   `"foo.txt"`,
+	"binary":       `[1, 2, 3]`,
+	"clearCookie":  `"foo"`,
+	"cookie":       `"foo", "bar"`,
+	"download":     `"missing.txt"`,
+	"end":          ``,
+	"flush":        ``,
+	"html":         `"<html></html>"`,
+	"json":         `{foo: "bar"}`,
+	"jsonArray":    `[1, 2]`,
+	"jsonp":        `{foo: "bar"}`,
+	"links":        `{next: "/foo?page=2"}`,
+	"location":     `"/foo"`,
+	"ndjson":       `[1, 2]`,
+	"redirect":     `"/foo"`,
+	"removeHeader": `"X-Append"`,
+	"render":       `"missing", () => {}`,
+	"send":         `"Hello"`,
+	"sendFile":     `"/missing.txt"`,
+	"sendStatus":   `204`,
+	"set":          `"X-Set", "foo"`,
+	"status":       `200`,
+	"text":         `"Hello, %s!", "World"`,
+	"type":         `"text/plain"`,
+	"vary":         `"Accept"`,
+	"write":        `"Hello"`,
+	"xml":          `{foo: "bar"}`,
+}
+
+var memberRE = regexp.MustCompile(`(?m)^  (?:readonly )?(\w+)\??: (.+);$`)
+
+// declaredMembers returns members of the given interface from TypeScript declarations with their types.
+func declaredMembers(t *testing.T, name string) map[string]string {
+	t.Helper()
+
+	decl := string(muxpress.Declarations)
+	start := strings.Index(decl, "export interface "+name+" {")
+
+	assert.GreaterOrEqual(t, start, 0, "missing interface declaration: %s", name)
+
+	end := strings.Index(decl[start:], "\n}\n")
+
+	members := map[string]string{}
+
+	for _, match := range memberRE.FindAllStringSubmatch(decl[start:start+end], -1) {
+		members[match[1]] = match[2]
+	}
+
+	return members
+}
+
+func isChainable(typ string) bool {
+	returns := strings.Split(typ, "=> ")[1:]
+
+	for _, ret := range returns {
+		if !strings.HasPrefix(ret, "Response") {
+			return false
+		}
+	}
+
+	return len(returns) != 0
+}
+
+func TestResponseShape(t *testing.T) {
+	t.Parallel()
+
+	members := declaredMembers(t, "Response")
+
+	assert.NotEmpty(t, members)
+
+	methods := []string{}
+	properties := []string{}
+	chainable := []string{}
+	chain := strings.Builder{}
+
+	for name, typ := range members {
+		if !strings.Contains(typ, "=>") {
+			properties = append(properties, name)
+
+			continue
+		}
+
+		methods = append(methods, name)
+
+		if !isChainable(typ) {
+			continue
+		}
+
+		args, found := chainArgs[name]
+
+		assert.True(t, found, "missing arguments for chainable method: %s", name)
+
+		chainable = append(chainable, name)
+
+		chain.WriteString("\t" + marshal(t, name) + ": (res) => res." + name + "(" + args + "),\n")
+	}
+
+	js(t, `
+// js
+const app = new Application()
+
+const methods = `+marshal(t, methods)+`
+const properties = `+marshal(t, properties)+`
+const chainable = `+marshal(t, chainable)+`
+const chained = {}
+const types = {}
+
+const calls = {
+`+chain.String()+`}
+
+app.get('/', (req, res) => {
+	methods.concat(properties).forEach(name => types[name] = typeof res[name])
+	res.end()
+})
+
+app.get('/chain/:name', (req, res) => {
+	chained[req.params.name] = calls[req.params.name](res) === res
+	res.end()
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('shape', () => {
+	assert.Equal(200, client.R().Get('/').GetStatusCode())
+
+	methods.forEach(name => assert.Equal('function', types[name], name))
+	properties.forEach(name => assert.NotEqual('undefined', types[name], name))
+})
+
+test('chainable', () => {
+	chainable.forEach(name => {
+		client.R().Get('/chain/' + name)
+		assert.True(chained[name], name)
+	})
+})
+
+// !js
+`)
+}
+
+func TestResponseChaining(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/missing', (req, res) => {
+	res.status(404).set('X-Reason', 'missing').json({ error: 'not found' })
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('chain', () => {
+	const resp = client.R().Get('/missing')
+	assert.Equal(404, resp.GetStatusCode())
+	assert.Equal('missing', resp.GetHeader('X-Reason'))
+	assert.Equal('not found', JSON.parse(resp.ToString()).error)
+})
+
+// !js
+`)
+}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	bin, err := json.Marshal(v)
+
+	assert.NoError(t, err)
+
+	return string(bin)
+}
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestSelfSigned(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.text('Hello, %s!', req.protocol)
+})
+
+app.listen({ tls: { selfSigned: true } }, () => {
+	client.SetBaseURL('https://' + app.host).SetRootCertFromString(app.ca)
+})
+
+test('https', () => {
+	const resp = client.R().Get('/')
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal('Hello, https!', resp.ToString())
+})
+
+test('CA', () => {
+	assert.True(app.ca.startsWith('-----BEGIN CERTIFICATE-----'))
+})
+
+// !js
+`)
+}
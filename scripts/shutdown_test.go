@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.text('ok')
+})
+
+app.listen(0, '127.0.0.1', () => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('serving', () => {
+	assert.Equal(200, client.R().Get('/').StatusCode)
+})
+
+test('shutdown', () => {
+	const host = app.host
+	let stopped = false
+	const promise = app.shutdown('1s', (err) => {
+		assert.Nil(err)
+		stopped = true
+	})
+
+	assert.True(promise instanceof Promise)
+	assert.True(stopped)
+	assert.Equal(host, app.host)
+})
+
+test('stopped', () => {
+	let refused = false
+	try {
+		client.R().Get('/')
+	} catch (e) {
+		refused = true
+	}
+	assert.True(refused)
+})
+
+test('idempotent', () => {
+	let called = false
+	app.shutdown(() => called = true)
+	assert.True(called)
+})
+
+// !js
+`)
+}
+
+func TestShutdownFromRoute(t *testing.T) {
+	t.Parallel()
+
+	var host, probe string
+
+	stopped := make(chan string, 1)
+
+	globals := map[string]interface{}{
+		"ready":   func(h, p string) { host, probe = h, p },
+		"stopped": func(reason string) { stopped <- reason },
+	}
+
+	jsWith(t, globals, `
+// js
+const app = new Application()
+
+app.post('/shutdown', (req, res) => {
+	app.shutdown((err) => stopped(String(err)))
+	res.text('bye')
+})
+
+app.listen(0, '127.0.0.1')
+
+// Without event loop the shutdown callback is called when the JavaScript thread is next used, like by another application.
+const other = new Application()
+
+other.get('/', (req, res) => res.text('ok'))
+other.listen(0, '127.0.0.1')
+
+ready(app.host, other.host)
+
+// !js
+`)
+
+	// The client runs after the script, so the runtime is used only by the servers.
+	resp, err := http.Post("http://"+host+"/shutdown", "text/plain", nil) //nolint:noctx
+
+	assert.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bye", string(body))
+
+	resp.Body.Close()
+
+	var reason string
+
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + probe + "/") //nolint:noctx
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case reason = <-stopped:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond, "shutdown callback not called")
+
+	assert.Equal(t, "undefined", reason)
+
+	_, err = http.Get("http://" + host + "/") //nolint:noctx,bodyclose
+
+	assert.Error(t, err)
+}
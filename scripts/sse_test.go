@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestSSE(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/events', (req, res) => {
+	const stream = res.sse()
+
+	stream.comment('welcome')
+	stream.send('Hello')
+	stream.send({ id: '42', event: 'greeting', data: { name: req.lastEventId } })
+	stream.close()
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('events', () => {
+	const resp = client.R().SetHeader('Last-Event-ID', '41').Get('/events')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('text/event-stream', resp.GetHeader('Content-Type'))
+	assert.Equal('no-cache', resp.GetHeader('Cache-Control'))
+	assert.Equal(': welcome\n\ndata: Hello\n\nid: 42\nevent: greeting\ndata: {"name":"41"}\n\n', resp.ToString())
+})
+
+// !js
+`)
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.post('/created', (req, res) => {
+	res.status(201)
+	res.set('X-Id', '42')
+	res.end()
+})
+
+app.get('/old', (req, res) => {
+	res.redirect('/new')
+})
+
+app.get('/moved', (req, res) => {
+	res.redirect(301, '/new')
+})
+
+app.get('/new', (req, res) => {
+	res.text('new')
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('status and header', () => {
+	const resp = client.R().Post('/created')
+	assert.Equal(201, resp.GetStatusCode())
+	assert.Equal('42', resp.GetHeader('X-Id'))
+})
+
+test('redirect', () => {
+	const resp = client.R().Get('/old')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('new', resp.ToString())
+})
+
+test('redirect with status', () => {
+	const resp = client.R().Get('/moved')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('new', resp.ToString())
+})
+
+// !js
+`)
+}
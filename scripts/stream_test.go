@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.get('/chunked', (req, res) => {
+	res.type('text/plain')
+	for (let i = 0; i < 3; i++) {
+		res.write('chunk ' + i + '\n')
+	}
+	res.end('done')
+})
+
+app.get('/empty', (req, res) => {
+	res.status(204).end()
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('chunked', () => {
+	const resp = client.R().Get('/chunked')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('chunk 0\nchunk 1\nchunk 2\ndone', resp.ToString())
+	assert.Equal('chunked', resp.Response.TransferEncoding[0])
+})
+
+test('empty', () => {
+	const resp = client.R().Get('/empty')
+	assert.Equal(204, resp.GetStatusCode())
+	assert.Equal('', resp.ToString())
+})
+
+// !js
+`)
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/szkiba/muxpress"
+)
+
+func newCertificate(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"}, //nolint:exhaustruct
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), //nolint:exhaustruct
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}) //nolint:exhaustruct
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	cert, key := newCertificate(t)
+
+	filesystem := afero.NewMemMapFs()
+
+	assert.NoError(t, afero.WriteFile(filesystem, "/certs/server.crt", cert, 0o644))
+	assert.NoError(t, afero.WriteFile(filesystem, "/certs/server.key", key, 0o600))
+
+	jsWith(t, map[string]interface{}{"cert": string(cert)}, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.json({ protocol: req.protocol })
+})
+
+app.listen({ host: '127.0.0.1', tls: { cert: '/certs/server.crt', key: '/certs/server.key' } }, () => {
+	client.SetBaseURL('https://' + app.host).SetRootCertFromString(cert)
+})
+
+test('https', () => {
+	const resp = client.R().Get('/')
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal({ protocol: 'https' }, JSON.parse(resp.ToString()))
+})
+
+test('inline PEM', () => {
+	const other = new Application()
+	other.get('/', (req, res) => res.text('inline'))
+	other.listen({ host: '127.0.0.1', tls: { cert, key: '/certs/server.key' } })
+	assert.Equal('inline', client.R().Get('https://' + other.host + '/').ToString())
+	other.shutdown()
+})
+
+// !js
+`, muxpress.WithFS(filesystem))
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocket(t *testing.T) {
+	t.Parallel()
+	jsWith(t, map[string]interface{}{"path": filepath.Join(t.TempDir(), "app.sock")}, `
+// js
+const app = new Application()
+
+app.get('/', (req, res) => {
+	res.text('Hello from %s', app.host)
+})
+
+app.listen({ path }, () => {
+	client.SetUnixSocket(path).SetBaseURL('http://localhost')
+})
+
+test('unix socket', () => {
+	const resp = client.R().Get('/')
+	assert.Equal('Hello from ' + path, resp.ToString())
+})
+
+test('address', () => {
+	assert.Equal(path, app.host)
+	assert.Nil(app.hostname)
+	assert.Nil(app.port)
+})
+
+// !js
+`)
+}
@@ -35,11 +35,18 @@ func testFunction(t *testing.T, runtime *goja.Runtime) func(string, goja.Callabl
 	}
 }
 
-func js(t *testing.T, script string) {
+func js(t *testing.T, script string, option ...muxpress.Option) {
+	t.Helper()
+
+	jsWith(t, nil, script, option...)
+}
+
+// jsWith runs the script like js, with additional global values.
+func jsWith(t *testing.T, globals map[string]interface{}, script string, option ...muxpress.Option) {
 	t.Helper()
 
 	runtime := goja.New()
-	ctor, err := muxpress.NewApplicationConstructor(runtime)
+	ctor, err := muxpress.NewApplicationConstructor(runtime, option...)
 
 	assert.NoError(t, err)
 	assert.NoError(t, runtime.Set("console", newConsole(t, runtime)))
@@ -48,6 +55,10 @@ func js(t *testing.T, script string) {
 	assert.NoError(t, runtime.Set("client", req.NewClient()))
 	assert.NoError(t, runtime.Set("test", testFunction(t, runtime)))
 
+	for name, value := range globals {
+		assert.NoError(t, runtime.Set(name, value))
+	}
+
 	prog, err := goja.Compile(t.Name(), script, true)
 
 	assert.NoError(t, err, "JavaScript syntax error")
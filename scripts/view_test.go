@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/szkiba/muxpress"
+)
+
+func TestView(t *testing.T) {
+	t.Parallel()
+
+	filesystem := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"/templates/layout.html": `<html><title>{{.title}}</title>{{block "content" .}}{{end}}</html>`,
+		"/templates/login.html":  `{{define "content"}}<form><input name="client" value="{{.client}}"></form>{{end}}{{template "layout.html" .}}`,
+		"/templates/hello.txt":   `Hello, {{name}}!`,
+	}
+
+	for name, content := range files {
+		assert.NoError(t, afero.WriteFile(filesystem, name, []byte(content), 0o644))
+	}
+
+	js(t, `
+// js
+const app = new Application()
+
+app.set('views', '/templates')
+app.locals.title = 'Login'
+
+app.engine('txt', (source, options, callback) => {
+	callback(null, source.replace(/{{(\w+)}}/g, (_, key) => options[key]))
+})
+
+app.get('/login', (req, res) => {
+	res.render('login', { client: req.query.client_id })
+})
+
+app.get('/hello', (req, res) => {
+	res.type('text/plain')
+	res.render('hello.txt', { name: 'World' }, (err, text) => res.send(text))
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('settings', () => {
+	assert.Equal('/templates', app.get('views'))
+	assert.Equal(true, app.get('view cache'))
+	assert.True(app.set('view cache', false) === app)
+})
+
+test('render', () => {
+	const resp = client.R().Get('/login?client_id="><script>')
+	assert.Equal(200, resp.GetStatusCode())
+	assert.Equal('text/html; charset=utf-8', resp.GetHeader('Content-Type'))
+	assert.Equal('<html><title>Login</title><form><input name="client" value="&#34;&gt;&lt;script&gt;"></form></html>', resp.ToString())
+})
+
+test('engine', () => {
+	const resp = client.R().Get('/hello')
+	assert.Equal('Hello, World!', resp.ToString())
+})
+
+// !js
+`, muxpress.WithFS(filesystem))
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocket(t *testing.T) {
+	t.Parallel()
+
+	var host string
+
+	ready := func(h string) { host = h }
+
+	jsWith(t, map[string]interface{}{"ready": ready}, `
+// js
+const app = new Application()
+
+app.use((req, res, next) => {
+	if (req.query.token != 'secret') {
+		res.status(401).text('Unauthorized')
+		return
+	}
+	next()
+})
+
+app.ws('/echo', (socket, req) => {
+	socket.on('message', (data, binary) => {
+		socket.send(binary ? 'binary ' + new Uint8Array(data).length : 'echo ' + data)
+	})
+	socket.send('welcome ' + req.query.name)
+})
+
+app.get('/echo', (req, res) => {
+	res.text('plain ' + req.query.name)
+})
+
+app.listen(() => ready(app.host))
+
+// !js
+`)
+
+	// The client runs after the script, so the runtime is used only by the server.
+	_, resp, err := websocket.DefaultDialer.Dial("ws://"+host+"/echo", nil)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp.Body.Close()
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://"+host+"/echo?token=secret&name=joe", nil)
+
+	assert.NoError(t, err)
+
+	resp.Body.Close()
+
+	defer conn.Close()
+
+	resp, err = http.Get("http://" + host + "/echo?token=secret&name=joe") //nolint:noctx
+
+	assert.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "plain joe", string(body))
+
+	resp.Body.Close()
+
+	_, data, err := conn.ReadMessage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "welcome joe", string(data))
+
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+
+	_, data, err = conn.ReadMessage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "echo hello", string(data))
+
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3}))
+
+	_, data, err = conn.ReadMessage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "binary 3", string(data))
+}
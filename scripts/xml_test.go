@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package scripts_test
+
+import "testing"
+
+func TestXML(t *testing.T) {
+	t.Parallel()
+	js(t, `
+// js
+const app = new Application()
+
+app.post('/soap', (req, res) => {
+	const user = req.body.Envelope.Body.GetUser
+	res.xml({ GetUserResponse: { '@id': user['@id'], name: 'Joe', roles: ['admin', 'user'] } })
+})
+
+app.get('/raw', (req, res) => {
+	res.xml('<ok/>')
+})
+
+app.get('/negotiate', (req, res) => {
+	res.send({ greeting: 'Hello' })
+})
+
+app.listen(() => {
+	client.SetBaseURL('http://' + app.host)
+})
+
+test('request and response', () => {
+	const resp = client.R()
+		.SetHeader('Content-Type', 'text/xml; charset=utf-8')
+		.SetBodyString('<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetUser id="42"/></soap:Body></soap:Envelope>')
+		.Post('/soap')
+	assert.Equal('application/xml; charset=utf-8', resp.GetHeader('Content-Type'))
+	assert.Equal('<?xml version="1.0" encoding="UTF-8"?>\n<GetUserResponse id="42"><name>Joe</name><roles>admin</roles><roles>user</roles></GetUserResponse>', resp.ToString())
+})
+
+test('string', () => {
+	assert.Equal('<ok/>', client.R().Get('/raw').ToString())
+})
+
+test('negotiation', () => {
+	let resp = client.R().SetHeader('Accept', 'application/xml').Get('/negotiate')
+	assert.Equal('application/xml; charset=utf-8', resp.GetHeader('Content-Type'))
+	assert.Equal('Accept', resp.GetHeader('Vary'))
+
+	resp = client.R().SetHeader('Accept', 'application/json').Get('/negotiate')
+	assert.Equal({ greeting: 'Hello' }, JSON.parse(resp.ToString()))
+})
+
+// !js
+`)
+}
@@ -6,87 +6,332 @@ package muxpress
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"log"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type server struct {
 	logger  logrus.FieldLogger
 	context func() context.Context
-	stopCh  chan struct{}
+
+	stopCh   chan time.Duration
+	stopOnce sync.Once
+	doneCh   chan struct{}
+	err      error
+
+	shutdownTimeout time.Duration
+	hooks           *serverHooks
 }
 
 func newServer(context func() context.Context, logger logrus.FieldLogger) *server {
-	srv := &server{
+	srv := &server{ //nolint:exhaustruct
 		context: context,
 		logger:  logger,
-		stopCh:  make(chan struct{}),
+		stopCh:  make(chan time.Duration, 1),
+
+		shutdownTimeout: defaultShutdownTimeout,
 	}
 
 	return srv
 }
 
-func (s *server) serve(listener net.Listener, handler http.Handler) {
+// serverOptions contains the per listener configuration of the HTTP server.
+type serverOptions struct {
+	tlsConfig *tls.Config
+	http2     *http2.Server
+	h2c       bool
+	http3     bool
+	hooks     *serverHooks
+
+	limits
+}
+
+// serverHooks are called on connection and server lifecycle changes. The hooks are called from the server goroutines.
+type serverHooks struct {
+	onConnection  func(local, remote net.Addr)
+	onClientError func(message string)
+	onError       func(err error)
+	onClose       func()
+}
+
+// clientErrorPrefixes are the prefixes of the HTTP server error log messages caused by clients.
+var clientErrorPrefixes = []string{
+	"http: TLS handshake error",
+	"http: URL query contains semicolon",
+	"http2: server: error reading preface from client",
+	"http2: server connection error from",
+	"http2: timeout waiting for SETTINGS frames from",
+	"http3: handling connection failed",
+}
+
+// errorLogWriter logs the messages of the HTTP server error log. Messages caused by clients are reported as client errors,
+// the others (like accept errors or handler panics) as server errors.
+type errorLogWriter struct {
+	logger logrus.FieldLogger
+	hooks  *serverHooks
+}
+
+func (w errorLogWriter) Write(data []byte) (int, error) {
+	message := strings.TrimSpace(string(data))
+
+	if isClientError(message) {
+		w.logger.Warn(message)
+		w.hooks.onClientError(message)
+	} else {
+		w.logger.Error(message)
+		w.hooks.onError(errors.New(message)) //nolint:goerr113
+	}
+
+	return len(data), nil
+}
+
+func isClientError(message string) bool {
+	for _, prefix := range clientErrorPrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *server) newHTTPServer(handler http.Handler, opts *serverOptions) (*http.Server, error) {
 	srv := new(http.Server)
 	srv.Handler = handler
 
-	errCh := make(chan error)
+	if opts == nil {
+		return srv, nil
+	}
+
+	srv.TLSConfig = opts.tlsConfig
+
+	opts.limits.apply(srv)
+
+	if opts.hooks != nil {
+		srv.ErrorLog = log.New(errorLogWriter{logger: s.logger, hooks: opts.hooks}, "", 0)
+		srv.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				opts.hooks.onConnection(conn.LocalAddr(), conn.RemoteAddr())
+			}
+		}
+	}
+
+	h2s := opts.http2
+	if h2s == nil {
+		h2s = new(http2.Server)
+	}
+
+	if opts.http2 != nil && opts.tlsConfig != nil {
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.h2c {
+		srv.Handler = h2c.NewHandler(handler, h2s)
+	}
+
+	return srv, nil
+}
+
+// serve runs the server (and the HTTP/3 server, if any) until it is stopped, the context is done or serving fails.
+// The error of serving or shutdown is recorded before doneCh is closed.
+func (s *server) serve(listener net.Listener, srv *http.Server, h3 *http3Listener) {
+	defer func() {
+		if s.hooks != nil {
+			s.hooks.onClose()
+		}
+
+		close(s.doneCh)
+	}()
+
+	if h3 != nil {
+		defer func() {
+			if err := h3.Close(); err != nil && s.err == nil {
+				s.fail(err)
+			}
+		}()
+	}
+
+	errCh := make(chan error, 2) //nolint:gomnd
 
 	go func() {
 		s.logger.Debug("server started")
-		errCh <- srv.Serve(listener)
+
+		if srv.TLSConfig != nil {
+			errCh <- srv.ServeTLS(listener, "", "")
+		} else {
+			errCh <- srv.Serve(listener)
+		}
 	}()
 
-	var err error
+	if h3 != nil {
+		go func() { errCh <- h3.serve() }()
+	}
 
-	ctx := s.context()
+	timeout := s.shutdownTimeout
 
 	select {
-	case <-s.stopCh:
-		break
-	case <-ctx.Done():
-		break
-	case err = <-errCh:
+	case t := <-s.stopCh:
+		if t >= 0 {
+			timeout = t
+		}
+	case <-s.context().Done():
 		break
+	case err := <-errCh:
+		s.logger.WithError(err).Error("server aborted")
+
+		srv.Close()
+
+		s.fail(err)
+
+		return
 	}
 
+	err := s.drainAll(timeout, srv, h3)
+
 	if err != nil {
-		s.logger.WithError(err).Error("server aborted")
+		s.logger.WithError(err).Error("server shutdown failed")
 
-		return
+		s.fail(err)
+	}
+
+	s.logger.Debug("server stopped")
+}
+
+// drainer is a server which can be shut down gracefully, like http.Server.
+type drainer interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// drainAll drains the servers concurrently and returns the first error.
+func (s *server) drainAll(timeout time.Duration, srv *http.Server, h3 *http3Listener) error {
+	if h3 == nil {
+		return s.drain(srv, timeout)
+	}
+
+	h3Err := make(chan error, 1)
+
+	go func() { h3Err <- s.drain(h3, timeout) }()
+
+	err := s.drain(srv, timeout)
+
+	if err2 := <-h3Err; err == nil {
+		err = err2
+	}
+
+	return err
+}
+
+// drain shuts the server down gracefully, active connections are closed after the timeout.
+// Zero timeout means closing active connections immediately, without draining.
+func (s *server) drain(srv drainer, timeout time.Duration) error {
+	if timeout == 0 {
+		return srv.Close()
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
-		s.logger.WithError(err).Errorf("server shutdown failed")
+	err := srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.logger.Debug("shutdown timeout exceeded, closing active connections")
+
+		err = srv.Close()
 	}
 
-	s.logger.Debug("server stopped")
+	return err
 }
 
-func (s *server) listenAndServe(addr string, handler http.Handler) (*net.TCPAddr, error) {
-	listener, err := net.Listen("tcp", addr)
+// listenAndServeWith listens on the network address and serves connections in the background, see serveWith.
+func (s *server) listenAndServeWith(network, addr string, handler http.Handler, opts *serverOptions) (net.Addr, error) {
+	listener, err := net.Listen(network, addr)
 	if err != nil {
 		return nil, err
 	}
 
-	s.stopCh = make(chan struct{})
+	if err := s.serveWith(listener, handler, opts); err != nil {
+		listener.Close()
+
+		return nil, err
+	}
+
+	return listener.Addr(), nil
+}
+
+// serveWith serves connections accepted by the listener in the background.
+// Connections are HTTPS if opts contains TLS configuration, or plain HTTP otherwise.
+func (s *server) serveWith(listener net.Listener, handler http.Handler, opts *serverOptions) error {
+	srv, err := s.newHTTPServer(handler, opts)
+	if err != nil {
+		return err
+	}
+
+	var h3 *http3Listener
+
+	if opts != nil && opts.http3 {
+		tcp, ok := listener.Addr().(*net.TCPAddr)
+		if !ok {
+			return errHTTP3WithoutTCP
+		}
+
+		h3, err = s.listenHTTP3(tcp, handler, opts)
+		if err != nil {
+			return err
+		}
+
+		srv.Handler = altSvcHandler(srv.Handler, tcp.Port)
+	}
+
+	if opts != nil {
+		s.shutdownTimeout = opts.limits.shutdown()
+		s.hooks = opts.hooks
+	}
+
+	s.doneCh = make(chan struct{})
+
+	go s.serve(listener, srv, h3)
 
-	go s.serve(listener, handler)
+	return nil
+}
 
-	a, _ := listener.Addr().(*net.TCPAddr)
+// fail records the error and reports it via the error hook.
+func (s *server) fail(err error) {
+	s.err = err
 
-	return a, nil
+	if s.hooks != nil {
+		s.hooks.onError(err)
+	}
 }
 
-func (s *server) shutdown() {
-	s.stopCh <- struct{}{}
+// stop initiates the graceful shutdown of the server without waiting for it. Active connections are closed after the timeout,
+// negative timeout means the shutdown timeout of the server. Calling stop more than once (or on a server never started) has no effect.
+func (s *server) stop(timeout time.Duration) {
+	if s.doneCh == nil {
+		return
+	}
+
+	s.stopOnce.Do(func() { s.stopCh <- timeout })
 }
 
-const shutdownTimeout = 500 * time.Millisecond
+// wait waits until the server is stopped and returns the error of serving or shutdown, if any.
+func (s *server) wait() error {
+	if s.doneCh == nil {
+		return nil
+	}
+
+	<-s.doneCh
+
+	return s.err
+}
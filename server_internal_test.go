@@ -6,11 +6,12 @@ package muxpress
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
-	"runtime"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -27,10 +28,14 @@ func newHelloHandler(t *testing.T) http.Handler {
 	})
 }
 
-func serverRequest(t *testing.T, addr *net.TCPAddr, path string) (*http.Response, error) { //nolint:unparam
+func serverRequest(t *testing.T, addr net.Addr, path string) (*http.Response, error) { //nolint:unparam
 	t.Helper()
 
-	a := net.JoinHostPort(addr.IP.String(), strconv.Itoa(addr.Port))
+	tcp, ok := addr.(*net.TCPAddr)
+
+	assert.True(t, ok)
+
+	a := net.JoinHostPort(tcp.IP.String(), strconv.Itoa(tcp.Port))
 	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, fmt.Sprintf("http://%s/%s", a, path), nil)
 
 	assert.NoError(t, err)
@@ -38,15 +43,17 @@ func serverRequest(t *testing.T, addr *net.TCPAddr, path string) (*http.Response
 	return http.DefaultClient.Do(req)
 }
 
-func Test_server_listenAndServe(t *testing.T) {
+func Test_server_listenAndServeWith(t *testing.T) {
 	t.Parallel()
 
 	srv := newServer(context.TODO, logrus.StandardLogger())
 
-	addr, err := srv.listenAndServe("", newHelloHandler(t))
+	addr, err := srv.listenAndServeWith("tcp", "", newHelloHandler(t), nil)
 
 	assert.NoError(t, err)
-	assert.Greater(t, addr.Port, 0)
+	assert.Greater(t, addr.(*net.TCPAddr).Port, 0) //nolint:forcetypeassert
+
+	defer srv.stop(-1)
 
 	res, err := serverRequest(t, addr, "/")
 	defer func() {
@@ -60,12 +67,16 @@ func Test_server_listenAndServe(t *testing.T) {
 	assert.Equal(t, "text/plain", res.Header.Get("content-type"))
 }
 
-func Test_server_shutdown(t *testing.T) {
+func Test_server_stop(t *testing.T) {
 	t.Parallel()
 
 	srv := newServer(context.TODO, logrus.StandardLogger())
 
-	addr, err := srv.listenAndServe("", newHelloHandler(t))
+	srv.stop(-1)
+
+	assert.NoError(t, srv.wait(), "never started")
+
+	addr, err := srv.listenAndServeWith("tcp", "", newHelloHandler(t), nil)
 
 	assert.NoError(t, err)
 
@@ -74,11 +85,15 @@ func Test_server_shutdown(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 
-	srv.shutdown()
+	res.Body.Close()
+
+	srv.stop(-1)
+
+	assert.NoError(t, srv.wait())
 
-	runtime.Gosched()
+	srv.stop(-1)
 
-	time.Sleep(100 * time.Microsecond) // XXX: should find a better solution
+	assert.NoError(t, srv.wait(), "already stopped")
 
 	res, err = serverRequest(t, addr, "/")
 	defer func() {
@@ -90,6 +105,171 @@ func Test_server_shutdown(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// recordingHooks records the server hook calls.
+type recordingHooks struct {
+	mu           sync.Mutex
+	connections  []net.Addr
+	clientErrors []string
+	errors       []error
+}
+
+func (rec *recordingHooks) hooks() *serverHooks {
+	return &serverHooks{
+		onConnection: func(_, remote net.Addr) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+
+			rec.connections = append(rec.connections, remote)
+		},
+		onClientError: func(message string) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+
+			rec.clientErrors = append(rec.clientErrors, message)
+		},
+		onError: func(err error) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+
+			rec.errors = append(rec.errors, err)
+		},
+		onClose: func() {},
+	}
+}
+
+func newSlowHandler(t *testing.T, started chan struct{}, delay time.Duration) http.Handler {
+	t.Helper()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(delay)
+		w.Write([]byte("done")) // nolint:errcheck
+	})
+}
+
+func Test_server_shutdown_drain(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	srv := newServer(context.TODO, logrus.StandardLogger())
+
+	addr, err := srv.listenAndServeWith("tcp", "", newSlowHandler(t, started, 100*time.Millisecond), nil)
+
+	assert.NoError(t, err)
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		res, err := serverRequest(t, addr, "/")
+		if err == nil {
+			res.Body.Close()
+		}
+
+		errCh <- err
+	}()
+
+	<-started
+
+	srv.stop(time.Second)
+
+	assert.NoError(t, srv.wait())
+	assert.NoError(t, <-errCh, "in-flight request should be finished")
+}
+
+func Test_server_shutdown_timeout(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	srv := newServer(context.TODO, logrus.StandardLogger())
+
+	addr, err := srv.listenAndServeWith("tcp", "", newSlowHandler(t, started, time.Second), nil)
+
+	assert.NoError(t, err)
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		res, err := serverRequest(t, addr, "/")
+		if err == nil {
+			res.Body.Close()
+		}
+
+		errCh <- err
+	}()
+
+	<-started
+
+	begin := time.Now()
+
+	srv.stop(10 * time.Millisecond)
+
+	assert.NoError(t, srv.wait())
+	assert.Less(t, time.Since(begin), 500*time.Millisecond)
+	assert.Error(t, <-errCh, "active connection should be closed")
+}
+
+func Test_server_shutdown_immediate(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	srv := newServer(context.TODO, logrus.StandardLogger())
+	opts := &serverOptions{limits: limits{shutdownTimeout: durationOf(0)}} //nolint:exhaustruct
+
+	bound, err := srv.listenAndServeWith("tcp", "127.0.0.1:0", newSlowHandler(t, started, time.Second), opts)
+
+	assert.NoError(t, err)
+
+	addr, _ := bound.(*net.TCPAddr)
+	errCh := make(chan error, 1)
+
+	go func() {
+		res, err := serverRequest(t, addr, "/")
+		if err == nil {
+			res.Body.Close()
+		}
+
+		errCh <- err
+	}()
+
+	<-started
+
+	begin := time.Now()
+
+	srv.stop(-1)
+
+	assert.NoError(t, srv.wait())
+	assert.Less(t, time.Since(begin), 500*time.Millisecond)
+	assert.Error(t, <-errCh, "active connection should be closed without draining")
+}
+
+// failingListener fails on accept.
+type failingListener struct {
+	net.Listener
+}
+
+func (l failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("accept failed") //nolint:goerr113
+}
+
+func Test_server_serve_failed(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer(context.TODO, logrus.StandardLogger())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+
+	defer listener.Close()
+
+	assert.NoError(t, srv.serveWith(failingListener{listener}, newHelloHandler(t), nil))
+	assert.EqualError(t, srv.wait(), "accept failed")
+
+	srv.stop(-1)
+
+	assert.EqualError(t, srv.wait(), "accept failed", "already stopped")
+}
+
 func Test_server_context_done(t *testing.T) {
 	t.Parallel()
 
@@ -97,7 +277,7 @@ func Test_server_context_done(t *testing.T) {
 
 	srv := newServer(func() context.Context { return ctx }, logrus.StandardLogger())
 
-	addr, err := srv.listenAndServe("", newHelloHandler(t))
+	addr, err := srv.listenAndServeWith("tcp", "", newHelloHandler(t), nil)
 
 	assert.NoError(t, err)
 
@@ -113,9 +293,7 @@ func Test_server_context_done(t *testing.T) {
 
 	cancel()
 
-	runtime.Gosched()
-
-	time.Sleep(100 * time.Microsecond) // XXX: should find a better solution
+	assert.NoError(t, srv.wait())
 
 	res, err = serverRequest(t, addr, "/")
 	defer func() {
@@ -132,11 +310,37 @@ func Test_server_serve_used_port(t *testing.T) {
 
 	srv := newServer(context.TODO, logrus.StandardLogger())
 
-	addr, err := srv.listenAndServe("", newHelloHandler(t))
+	addr, err := srv.listenAndServeWith("tcp", "", newHelloHandler(t), nil)
 
 	assert.NoError(t, err)
 
-	_, err = srv.listenAndServe(":"+strconv.Itoa(addr.Port), newHelloHandler(t))
+	defer srv.stop(-1)
+
+	_, err = newServer(context.TODO, logrus.StandardLogger()).listenAndServeWith("tcp", addr.String(), newHelloHandler(t), nil)
 
 	assert.Error(t, err)
 }
+
+func Test_errorLogWriter(t *testing.T) {
+	t.Parallel()
+
+	rec := new(recordingHooks)
+	writer := errorLogWriter{logger: logrus.StandardLogger(), hooks: rec.hooks()}
+
+	fmt.Fprintln(writer, "http: TLS handshake error from 127.0.0.1:1234: EOF")
+	fmt.Fprintln(writer, "http2: server connection error from 127.0.0.1:1234: connection error: PROTOCOL_ERROR")
+	fmt.Fprintln(writer, "http: Accept error: too many open files; retrying in 5ms")
+	fmt.Fprintln(writer, "http: panic serving 127.0.0.1:1234: boom")
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	assert.Equal(t, []string{
+		"http: TLS handshake error from 127.0.0.1:1234: EOF",
+		"http2: server connection error from 127.0.0.1:1234: connection error: PROTOCOL_ERROR",
+	}, rec.clientErrors)
+
+	assert.Len(t, rec.errors, 2)
+	assert.EqualError(t, rec.errors[0], "http: Accept error: too many open files; retrying in 5ms")
+	assert.EqualError(t, rec.errors[1], "http: panic serving 127.0.0.1:1234: boom")
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// sse turns the response into a Server-Sent Events stream and returns the event stream object.
+// The response is kept open until the stream is closed or the client disconnects.
+func (resp *response) sse(options goja.Value) *goja.Object {
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+
+	resp.flush()
+
+	stream := newEventStream(resp)
+
+	if obj, ok := options.(*goja.Object); ok {
+		if v := obj.Get("heartbeat"); isSet(v) && v.ToInteger() > 0 {
+			go stream.heartbeat(time.Duration(v.ToInteger()) * time.Millisecond)
+		}
+	}
+
+	resp.onEnded(stream.closed)
+
+	return wrapEventStream(resp.runtime, stream)
+}
+
+func wrapEventStream(runtime *goja.Runtime, stream *eventStream) *goja.Object {
+	this := runtime.NewObject()
+
+	stream.this = this
+
+	mustSet(runtime, this, "send", stream.send)
+	mustSet(runtime, this, "comment", stream.comment)
+	mustSet(runtime, this, "close", stream.close)
+	mustSet(runtime, this, "on", stream.on)
+
+	return this
+}
+
+type eventStream struct {
+	resp    *response
+	runtime *goja.Runtime
+	this    *goja.Object
+
+	mu      sync.Mutex
+	onClose []goja.Callable
+}
+
+func newEventStream(resp *response) *eventStream {
+	return &eventStream{resp: resp, runtime: resp.runtime} //nolint:exhaustruct
+}
+
+// send sends an event. The event parameter is either an object with event, data, id and retry properties or the data itself.
+// Non-string data is serialized as JSON.
+func (stream *eventStream) send(event goja.Value) *goja.Object {
+	var buff bytes.Buffer
+
+	obj, isObject := event.(*goja.Object)
+	if !isObject || !isEventObject(obj) {
+		writeEventData(&buff, stream.eventData(event))
+		must(stream.runtime, stream.resp.stream(buff.Bytes()))
+
+		return stream.this
+	}
+
+	if v := obj.Get("id"); isSet(v) {
+		writeEventField(&buff, "id", v.String())
+	}
+
+	if v := obj.Get("event"); isSet(v) {
+		writeEventField(&buff, "event", v.String())
+	}
+
+	if v := obj.Get("retry"); isSet(v) {
+		writeEventField(&buff, "retry", strconv.FormatInt(v.ToInteger(), 10))
+	}
+
+	if v := obj.Get("data"); isSet(v) {
+		writeEventData(&buff, stream.eventData(v))
+	} else {
+		buff.WriteString("\n")
+	}
+
+	must(stream.runtime, stream.resp.stream(buff.Bytes()))
+
+	return stream.this
+}
+
+// comment sends a comment line, usually used as heartbeat to keep the connection alive.
+func (stream *eventStream) comment(text string) *goja.Object {
+	must(stream.runtime, stream.resp.stream(formatComment(text)))
+
+	return stream.this
+}
+
+func (stream *eventStream) close() *goja.Object {
+	stream.resp.end(goja.Undefined())
+
+	return stream.this
+}
+
+// on registers event handler. The only supported event is close, which is emitted when the stream is closed or the client disconnects.
+func (stream *eventStream) on(name string, handler goja.Callable) *goja.Object {
+	if name != "close" {
+		throwf(stream.runtime, "unsupported event: %s", name)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	stream.onClose = append(stream.onClose, handler)
+
+	return stream.this
+}
+
+func (stream *eventStream) eventData(value goja.Value) string {
+	if str, ok := value.Export().(string); ok {
+		return str
+	}
+
+	bin, err := json.Marshal(value.Export())
+
+	must(stream.runtime, err)
+
+	return string(bin)
+}
+
+// closed calls the close handlers, it is called on the JavaScript thread when the response is ended or the client is gone.
+func (stream *eventStream) closed() error {
+	stream.mu.Lock()
+	handlers := stream.onClose
+	stream.mu.Unlock()
+
+	for _, handler := range handlers {
+		if _, err := handler(stream.this); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (stream *eventStream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var gone <-chan struct{}
+
+	if stream.resp.request != nil {
+		gone = stream.resp.request.Context().Done()
+	}
+
+	for {
+		select {
+		case <-stream.resp.done:
+			return
+		case <-gone:
+			return
+		case <-ticker.C:
+			if err := stream.resp.stream(formatComment("")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func isEventObject(obj *goja.Object) bool {
+	for _, key := range obj.Keys() {
+		switch key {
+		case "id", "event", "retry", "data":
+		default:
+			return false
+		}
+	}
+
+	return len(obj.Keys()) != 0
+}
+
+func writeEventField(buff *bytes.Buffer, name string, value string) {
+	buff.WriteString(name)
+	buff.WriteString(": ")
+	buff.WriteString(strings.NewReplacer("\r", "", "\n", "").Replace(value))
+	buff.WriteString("\n")
+}
+
+func writeEventData(buff *bytes.Buffer, data string) {
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		writeEventField(buff, "data", line)
+	}
+
+	buff.WriteString("\n")
+}
+
+func formatComment(text string) []byte {
+	var buff bytes.Buffer
+
+	for _, line := range strings.Split(text, "\n") {
+		buff.WriteString(":")
+
+		if len(line) != 0 {
+			buff.WriteString(" ")
+			buff.WriteString(line)
+		}
+
+		buff.WriteString("\n")
+	}
+
+	buff.WriteString("\n")
+
+	return buff.Bytes()
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncRecorder is a goroutine-safe wrapper around httptest.ResponseRecorder.
+type syncRecorder struct {
+	*httptest.ResponseRecorder
+	mu sync.Mutex
+}
+
+func newSyncRecorder() *syncRecorder {
+	return &syncRecorder{ResponseRecorder: httptest.NewRecorder()} //nolint:exhaustruct
+}
+
+func (rec *syncRecorder) Write(data []byte) (int, error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.ResponseRecorder.Write(data)
+}
+
+func (rec *syncRecorder) body() string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.Body.String()
+}
+
+func Test_formatComment(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, ":\n\n", string(formatComment("")))
+	assert.Equal(t, ": foo\n\n", string(formatComment("foo")))
+	assert.Equal(t, ": foo\n: bar\n\n", string(formatComment("foo\nbar")))
+}
+
+func Test_writeEventData(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+
+	writeEventData(&buff, "foo\nbar")
+
+	assert.Equal(t, "data: foo\ndata: bar\n\n", buff.String())
+
+	buff.Reset()
+
+	writeEventField(&buff, "event", "foo\nbar")
+
+	assert.Equal(t, "event: foobar\n", buff.String())
+}
+
+func Test_response_sse(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	res.runner = syncRunner()
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	stream, isObject := callMethod(t, obj, "sse").(*goja.Object)
+
+	assert.True(t, isObject)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.True(t, rec.Flushed)
+
+	closed := make(chan struct{})
+
+	callMethod(t, stream, "on", value("close"), value(func() { close(closed) }))
+
+	callMethod(t, stream, "send", value("hello"))
+	callMethod(t, stream, "send", value(map[string]interface{}{"foo": "bar"}))
+	callMethod(t, stream, "send", value(map[string]interface{}{"id": "1", "event": "update", "retry": 1000, "data": "line1\nline2"}))
+	callMethod(t, stream, "comment", value("ping"))
+
+	select {
+	case <-res.done:
+		assert.Fail(t, "event stream should be kept open")
+	default:
+	}
+
+	res.runner(func() error {
+		callMethod(t, stream, "close")
+
+		return nil
+	})
+
+	<-res.done
+
+	res.wait()
+
+	select {
+	case <-closed:
+	default:
+		assert.Fail(t, "close handlers should be called before the request handling is finished")
+	}
+
+	expected := "data: hello\n\n" +
+		"data: {\"foo\":\"bar\"}\n\n" +
+		"id: 1\nevent: update\nretry: 1000\ndata: line1\ndata: line2\n\n" +
+		": ping\n\n"
+
+	assert.Equal(t, expected, rec.Body.String())
+}
+
+func Test_response_sse_disconnect(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := newSyncRecorder()
+	ctx, cancel := context.WithCancel(context.TODO())
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	res.runner = syncRunner()
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	options := runtime.NewObject()
+
+	assert.NoError(t, options.Set("heartbeat", 5))
+
+	stream, isObject := callMethod(t, obj, "sse", options).(*goja.Object)
+
+	assert.True(t, isObject)
+
+	closed := make(chan struct{})
+
+	callMethod(t, stream, "on", value("close"), value(func() { close(closed) }))
+
+	assert.Eventually(t, func() bool { return rec.body() == ":\n\n:\n\n" }, time.Second, time.Millisecond)
+
+	cancel()
+	res.wait()
+
+	select {
+	case <-closed:
+	default:
+		assert.Fail(t, "close handlers should be called before the request handling is finished")
+	}
+
+	res.runner(func() error {
+		call, _ := goja.AssertFunction(stream.Get("on"))
+		_, err := call(stream, value("dummy"), value(func() {}))
+
+		assert.Error(t, err)
+
+		return nil
+	})
+}
+
+func Test_request_lastEventId(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	from := httptest.NewRequest(http.MethodGet, "/", nil)
+	from.Header.Set("Last-Event-ID", "42")
+
+	req := wrapHTTPRequest(runtime, from)
+
+	assert.Equal(t, "42", req.Get("lastEventId").String())
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// jsThread runs functions on the JavaScript thread via the runner and tracks the running ones. It is shared by the
+// applications of a runtime. Tasks of the server goroutines (like events) are posted: with event loop they are delivered
+// in order via runner. The synchronous runner has no event loop, the JavaScript code may run concurrently on the main
+// script, so the posted tasks are queued and run at the beginning of the next function run on the JavaScript thread
+// (like a request handler).
+type jsThread struct {
+	runner      RunnerFunc
+	synchronous bool
+	logger      logrus.FieldLogger
+
+	running atomic.Int32 // number of functions running via runner
+
+	mu       sync.Mutex
+	pending  []func() error
+	draining bool
+}
+
+func newJSThread(runner RunnerFunc, synchronous bool, logger logrus.FieldLogger) *jsThread {
+	return &jsThread{runner: runner, synchronous: synchronous, logger: logger} //nolint:exhaustruct
+}
+
+// run calls fn via runner. Without event loop the queued tasks are run first.
+func (th *jsThread) run(fn func() error) {
+	th.runner(func() error {
+		th.running.Add(1)
+		defer th.running.Add(-1)
+
+		if th.synchronous {
+			th.flush()
+		}
+
+		return fn()
+	})
+}
+
+// active returns true if a function is running via runner, like a request handler.
+func (th *jsThread) active() bool {
+	return th.running.Load() != 0
+}
+
+// post queues the task without blocking, it is called by server goroutines.
+// With event loop a single goroutine delivers the queued tasks via runner, in the order of post.
+func (th *jsThread) post(task func() error) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.pending = append(th.pending, task)
+
+	if !th.synchronous && !th.draining {
+		th.draining = true
+
+		go th.drain()
+	}
+}
+
+// sync runs the queued tasks on the main script, without event loop. It must not be called via runner.
+func (th *jsThread) sync() {
+	if th.synchronous {
+		th.run(func() error { return nil })
+	}
+}
+
+func (th *jsThread) next() func() error {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	if len(th.pending) == 0 {
+		th.draining = false
+
+		return nil
+	}
+
+	task := th.pending[0]
+	th.pending = th.pending[1:]
+
+	return task
+}
+
+func (th *jsThread) drain() {
+	for task := th.next(); task != nil; task = th.next() {
+		th.run(task)
+	}
+}
+
+// flush runs the queued tasks, there is nobody to report their errors to, so they are logged.
+func (th *jsThread) flush() {
+	for task := th.next(); task != nil; task = th.next() {
+		if err := task(); err != nil {
+			th.logger.WithError(err).Error("deferred task failed")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_jsThread_run(t *testing.T) {
+	t.Parallel()
+
+	thread := newJSThread(syncRunner(), true, logrus.StandardLogger())
+
+	assert.False(t, thread.active())
+
+	thread.run(func() error {
+		assert.True(t, thread.active())
+
+		return nil
+	})
+
+	assert.False(t, thread.active())
+}
+
+func Test_jsThread_post_synchronous(t *testing.T) {
+	t.Parallel()
+
+	logger, hook := test.NewNullLogger()
+	thread := newJSThread(syncRunner(), true, logger)
+
+	var calls []string
+
+	thread.post(func() error {
+		calls = append(calls, "first")
+
+		return errors.New("boom") //nolint:goerr113
+	})
+
+	thread.post(func() error {
+		calls = append(calls, "second")
+
+		return nil
+	})
+
+	assert.Empty(t, calls)
+
+	thread.sync()
+
+	assert.Equal(t, []string{"first", "second"}, calls)
+	assert.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+
+	thread.sync()
+
+	assert.Len(t, calls, 2)
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+)
+
+const pemMarker = "-----BEGIN"
+
+var (
+	errNoCertificate = errors.New("no TLS certificate")
+	errInvalidCA     = errors.New("invalid CA certificate")
+	errNoClientCA    = errors.New("client certificate verification requires ca")
+	errClientAuth    = errors.New("invalid clientAuth, must be optional or required")
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"optional": tls.VerifyClientCertIfGiven,
+	"required": tls.RequireAndVerifyClientCert,
+}
+
+// tlsOptions contains the tls option of listen.
+// PEM values are either inline strings or file paths in the filesystem.
+type tlsOptions struct {
+	cert string
+	key  string
+	ca   []string
+
+	selfSigned bool
+	hosts      []string
+	clientAuth tls.ClientAuthType
+
+	certificates []tls.Certificate
+}
+
+var selfSignedHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+func parseTLSOptions(runtime *goja.Runtime, value goja.Value) *tlsOptions {
+	opts := new(tlsOptions)
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts
+	}
+
+	if v := obj.Get("cert"); isSet(v) {
+		opts.cert = v.String()
+	}
+
+	if v := obj.Get("key"); isSet(v) {
+		opts.key = v.String()
+	}
+
+	if v := obj.Get("ca"); isSet(v) {
+		if arr, ok := v.(*goja.Object); ok && isArray(arr) {
+			must(runtime, runtime.ExportTo(arr, &opts.ca))
+		} else {
+			opts.ca = []string{v.String()}
+		}
+	}
+
+	if v := obj.Get("clientAuth"); isSet(v) {
+		clientAuth, found := clientAuthTypes[v.String()]
+		if !found {
+			throw(runtime, errClientAuth)
+		}
+
+		opts.clientAuth = clientAuth
+	}
+
+	if v := obj.Get("selfSigned"); isSet(v) {
+		if arr, ok := v.(*goja.Object); ok && isArray(arr) {
+			must(runtime, runtime.ExportTo(arr, &opts.hosts))
+
+			opts.selfSigned = true
+		} else {
+			opts.selfSigned = v.ToBoolean()
+		}
+	}
+
+	return opts
+}
+
+// selfSignedHosts returns the host names for the self-signed certificate:
+// the given hosts or the local host names and the listening hostname.
+func (opts *tlsOptions) selfSignedHosts(hostname string) []string {
+	if len(opts.hosts) != 0 {
+		return opts.hosts
+	}
+
+	hosts := append([]string{}, selfSignedHosts...)
+
+	if len(hostname) == 0 {
+		return hosts
+	}
+
+	for _, host := range hosts {
+		if host == hostname {
+			return hosts
+		}
+	}
+
+	return append(hosts, hostname)
+}
+
+// config returns a clone of base (or a new configuration) extended with the certificate, the CA pool and the client authentication policy.
+func (opts *tlsOptions) config(base *tls.Config, filesystem afero.Fs) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12} //nolint:exhaustruct
+
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if len(opts.cert) != 0 || len(opts.key) != 0 {
+		certPEM, err := readPEM(filesystem, opts.cert)
+		if err != nil {
+			return nil, err
+		}
+
+		keyPEM, err := readPEM(filesystem, opts.key)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = append(config.Certificates, cert)
+	}
+
+	config.Certificates = append(config.Certificates, opts.certificates...)
+
+	if len(opts.ca) != 0 {
+		pool := x509.NewCertPool()
+
+		for _, ca := range opts.ca {
+			caPEM, err := readPEM(filesystem, ca)
+			if err != nil {
+				return nil, err
+			}
+
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return nil, errInvalidCA
+			}
+		}
+
+		config.ClientCAs = pool
+	}
+
+	if opts.clientAuth != tls.NoClientCert {
+		if config.ClientCAs == nil {
+			return nil, errNoClientCA
+		}
+
+		config.ClientAuth = opts.clientAuth
+	}
+
+	if len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return nil, errNoCertificate
+	}
+
+	return config, nil
+}
+
+// readPEM returns value itself if it contains PEM encoded data, otherwise reads the file named value.
+func readPEM(filesystem afero.Fs, value string) ([]byte, error) {
+	if strings.Contains(value, pemMarker) {
+		return []byte(value), nil
+	}
+
+	return afero.ReadFile(filesystem, value)
+}
@@ -0,0 +1,312 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCertificate returns a self-signed certificate and its key in PEM format.
+func newTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"}, //nolint:exhaustruct
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+
+	assert.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})   //nolint:exhaustruct
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}) //nolint:exhaustruct
+
+	return string(certPEM), string(keyPEM)
+}
+
+func tlsClient(t *testing.T, caPEM string) *http.Client {
+	t.Helper()
+
+	pool := x509.NewCertPool()
+
+	assert.True(t, pool.AppendCertsFromPEM([]byte(caPEM)))
+
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}} //nolint:exhaustruct
+
+	return &http.Client{Transport: transport} //nolint:exhaustruct
+}
+
+func Test_parseTLSOptions(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	value, err := runtime.RunString(`({cert: "cert.pem", key: "key.pem", ca: ["ca1.pem", "ca2.pem"]})`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &tlsOptions{cert: "cert.pem", key: "key.pem", ca: []string{"ca1.pem", "ca2.pem"}}, parseTLSOptions(runtime, value))
+
+	value, err = runtime.RunString(`({ca: "ca.pem"})`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ca.pem"}, parseTLSOptions(runtime, value).ca)
+	assert.Equal(t, new(tlsOptions), parseTLSOptions(runtime, goja.Undefined()))
+
+	value, err = runtime.RunString(`({selfSigned: true})`)
+
+	assert.NoError(t, err)
+	assert.True(t, parseTLSOptions(runtime, value).selfSigned)
+
+	value, err = runtime.RunString(`({selfSigned: ["example.com"]})`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &tlsOptions{selfSigned: true, hosts: []string{"example.com"}}, parseTLSOptions(runtime, value))
+
+	value, err = runtime.RunString(`({clientAuth: "optional"})`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, parseTLSOptions(runtime, value).clientAuth)
+}
+
+func Test_tlsOptions_selfSignedHosts(t *testing.T) {
+	t.Parallel()
+
+	opts := new(tlsOptions)
+
+	assert.Equal(t, selfSignedHosts, opts.selfSignedHosts(""))
+	assert.Equal(t, selfSignedHosts, opts.selfSignedHosts("127.0.0.1"))
+	assert.Equal(t, append(append([]string{}, selfSignedHosts...), "example.com"), opts.selfSignedHosts("example.com"))
+
+	opts.hosts = []string{"example.com"}
+
+	assert.Equal(t, []string{"example.com"}, opts.selfSignedHosts("localhost"))
+}
+
+func Test_tlsOptions_config(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCertificate(t)
+
+	filesystem := afero.NewMemMapFs()
+
+	assert.NoError(t, afero.WriteFile(filesystem, "/certs/cert.pem", []byte(certPEM), 0o644))
+	assert.NoError(t, afero.WriteFile(filesystem, "/certs/key.pem", []byte(keyPEM), 0o600))
+
+	opts := &tlsOptions{cert: "/certs/cert.pem", key: keyPEM, ca: []string{certPEM}}
+
+	config, err := opts.config(nil, filesystem)
+
+	assert.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.NotNil(t, config.ClientCAs)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+
+	base := &tls.Config{MinVersion: tls.VersionTLS13} //nolint:exhaustruct
+
+	config, err = (&tlsOptions{cert: certPEM, key: "/certs/key.pem"}).config(base, filesystem) //nolint:exhaustruct
+
+	assert.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.Empty(t, base.Certificates)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+
+	_, err = new(tlsOptions).config(nil, filesystem)
+
+	assert.ErrorIs(t, err, errNoCertificate)
+
+	_, err = (&tlsOptions{cert: certPEM, key: keyPEM, ca: []string{"/certs/key.pem"}}).config(nil, filesystem)
+
+	assert.ErrorIs(t, err, errInvalidCA)
+
+	_, err = (&tlsOptions{cert: "/missing.pem", key: keyPEM}).config(nil, filesystem) //nolint:exhaustruct
+
+	assert.Error(t, err)
+
+	_, err = (&tlsOptions{cert: certPEM, key: certPEM}).config(nil, filesystem) //nolint:exhaustruct
+
+	assert.Error(t, err)
+}
+
+func Test_application_listen_tls(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCertificate(t)
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime)
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+	assert.NoError(t, runtime.Set("cert", certPEM))
+	assert.NoError(t, runtime.Set("key", keyPEM))
+
+	host, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("Hello, %s!", req.protocol))
+	app.listen({ host: "127.0.0.1", tls: { cert, key } })
+	app.host
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	body := tlsGet(t, tlsClient(t, certPEM), "https://"+host.String()+"/")
+
+	assert.Equal(t, "Hello, https!", body)
+
+	_, err = runtime.RunString(`new Application().listen({ tls: {} })`)
+
+	assert.ErrorContains(t, err, errNoCertificate.Error())
+}
+
+func Test_WithTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCertificate(t)
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+
+	assert.NoError(t, err)
+
+	runtime := goja.New()
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12} //nolint:exhaustruct
+	ctor, err := NewApplicationConstructor(runtime, WithTLSConfig(config))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+
+	host, err := runtime.RunString(`
+	const app = new Application()
+	app.get("/", (req, res) => res.text("Hello"))
+	app.listen(0, "127.0.0.1")
+	app.host
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("app.shutdown()") //nolint:errcheck
+
+	assert.Equal(t, "Hello", tlsGet(t, tlsClient(t, certPEM), "https://"+host.String()+"/"))
+}
+
+func tlsGet(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
+
+	assert.NoError(t, err)
+
+	resp, err := client.Do(req)
+
+	assert.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+
+	return string(body)
+}
+
+func Test_application_listen_clientAuth(t *testing.T) {
+	t.Parallel()
+
+	ca, err := NewCertificateAuthority()
+
+	assert.NoError(t, err)
+
+	clientCert, err := ca.issue(x509.ExtKeyUsageClientAuth, "partner")
+
+	assert.NoError(t, err)
+
+	runtime := goja.New()
+	ctor, err := NewApplicationConstructor(runtime, WithCertificateAuthority(ca))
+
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.Set("Application", ctor))
+	assert.NoError(t, runtime.Set("ca", string(ca.CertificatePEM())))
+
+	value, err := runtime.RunString(`
+	const handler = (req, res) => res.text("%s", req.client ? req.client.commonName : "anonymous")
+
+	const required = new Application()
+	required.get("/", handler)
+	required.listen({ host: "127.0.0.1", tls: { selfSigned: true, ca, clientAuth: "required" } })
+
+	const optional = new Application()
+	optional.get("/", handler)
+	optional.listen({ host: "127.0.0.1", tls: { selfSigned: true, ca, clientAuth: "optional" } })
+
+	;[required.host, optional.host]
+	`)
+
+	assert.NoError(t, err)
+
+	defer runtime.RunString("required.shutdown(); optional.shutdown()") //nolint:errcheck
+
+	var hosts []string
+
+	assert.NoError(t, runtime.ExportTo(value, &hosts))
+
+	anonymous := tlsClient(t, string(ca.CertificatePEM()))
+	partner := tlsClient(t, string(ca.CertificatePEM()))
+	partner.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{clientCert} //nolint:forcetypeassert
+
+	assert.Equal(t, "partner", tlsGet(t, partner, "https://"+hosts[0]+"/"))
+	assert.Equal(t, "partner", tlsGet(t, partner, "https://"+hosts[1]+"/"))
+	assert.Equal(t, "anonymous", tlsGet(t, anonymous, "https://"+hosts[1]+"/"))
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, "https://"+hosts[0]+"/", nil)
+
+	assert.NoError(t, err)
+
+	_, err = anonymous.Do(req) //nolint:bodyclose
+
+	assert.Error(t, err)
+
+	_, err = runtime.RunString(`new Application().listen({ tls: { selfSigned: true, clientAuth: "required" } })`)
+
+	assert.ErrorContains(t, err, errNoClientCA.Error())
+
+	_, err = runtime.RunString(`new Application().listen({ tls: { selfSigned: true, ca, clientAuth: "maybe" } })`)
+
+	assert.ErrorContains(t, err, errClientAuth.Error())
+}
@@ -35,3 +35,63 @@ func mustSet(runtime *goja.Runtime, obj *goja.Object, name string, value interfa
 func mustSetGetter(runtime *goja.Runtime, obj *goja.Object, name string, getter interface{}) {
 	must(runtime, obj.DefineAccessorProperty(name, runtime.ToValue(getter), goja.Undefined(), goja.FLAG_FALSE, goja.FLAG_TRUE))
 }
+
+func isSet(value goja.Value) bool {
+	return value != nil && !goja.IsUndefined(value) && !goja.IsNull(value)
+}
+
+func isTrue(value goja.Value) bool {
+	return value != nil && value.ToBoolean()
+}
+
+// toBytes converts string, ArrayBuffer, typed array, DataView or array value to byte slice.
+// ArrayBuffer and views share the underlying buffer, no copy is made.
+func toBytes(runtime *goja.Runtime, value goja.Value) []byte {
+	switch val := value.Export().(type) {
+	case string:
+		return []byte(val)
+	case goja.ArrayBuffer:
+		return val.Bytes()
+	}
+
+	if data, ok := viewBytes(runtime, value); ok {
+		return data
+	}
+
+	var data []byte
+
+	must(runtime, runtime.ExportTo(value, &data))
+
+	return data
+}
+
+// viewBytes returns the bytes of a typed array or DataView (ArrayBuffer.isView).
+func viewBytes(runtime *goja.Runtime, value goja.Value) ([]byte, bool) {
+	obj, isObject := value.(*goja.Object)
+	if !isObject {
+		return nil, false
+	}
+
+	ctor, isObject := runtime.Get("ArrayBuffer").(*goja.Object)
+	if !isObject {
+		return nil, false
+	}
+
+	isView, isFunc := goja.AssertFunction(ctor.Get("isView"))
+	if !isFunc {
+		return nil, false
+	}
+
+	if ret, err := isView(ctor, obj); err != nil || !ret.ToBoolean() {
+		return nil, false
+	}
+
+	buff, ok := obj.Get("buffer").Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+
+	offset, length := obj.Get("byteOffset").ToInteger(), obj.Get("byteLength").ToInteger()
+
+	return buff.Bytes()[offset : offset+length], true
+}
@@ -50,3 +50,54 @@ func Test_must(t *testing.T) {
 	assert.NotPanics(t, func() { mustSetGetter(runtime, obj, "dynamic", func() string { return "value" }) })
 	assert.Equal(t, "value", obj.Get("dynamic").String())
 }
+
+func Test_isSet(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	assert.False(t, isSet(nil))
+	assert.False(t, isSet(goja.Undefined()))
+	assert.False(t, isSet(goja.Null()))
+	assert.True(t, isSet(runtime.ToValue(false)))
+
+	assert.False(t, isTrue(nil))
+	assert.False(t, isTrue(goja.Undefined()))
+	assert.False(t, isTrue(runtime.ToValue(false)))
+	assert.True(t, isTrue(runtime.ToValue(true)))
+}
+
+func Test_toBytes(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	assert.Equal(t, []byte("foo"), toBytes(runtime, runtime.ToValue("foo")))
+	assert.Equal(t, []byte{1, 2}, toBytes(runtime, runtime.ToValue(runtime.NewArrayBuffer([]byte{1, 2}))))
+
+	arr, err := runtime.RunString("[3, 4]")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{3, 4}, toBytes(runtime, arr))
+	assert.Panics(t, func() { toBytes(runtime, runtime.ToValue(runtime.NewObject())) })
+
+	buff := runtime.NewArrayBuffer([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+
+	assert.NoError(t, runtime.Set("buff", buff))
+
+	for script, expected := range map[string][]byte{
+		"new Uint8Array(buff)":             {0, 1, 2, 3, 4, 5, 6, 7},
+		"new Uint8Array(buff).subarray(2)": {2, 3, 4, 5, 6, 7},
+		"new Uint16Array(buff, 2, 2)":      {2, 3, 4, 5},
+		"new DataView(buff, 6)":            {6, 7},
+	} {
+		view, err := runtime.RunString(script)
+
+		assert.NoError(t, err)
+
+		data := toBytes(runtime, view)
+
+		assert.Equal(t, expected, data, script)
+		assert.Same(t, &buff.Bytes()[expected[0]], &data[0], "%s should not copy", script)
+	}
+}
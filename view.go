@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+)
+
+const (
+	settingViews       = "views"
+	settingViewEngine  = "view engine"
+	settingViewCache   = "view cache"
+	defaultViewsDir    = "views"
+	builtinViewEngine  = ".html"
+	viewEngineFilename = "filename"
+)
+
+var errNoViewEngine = errors.New("no view engine")
+
+// views renders templates from the filesystem using the built-in html/template engine or engines registered from JavaScript.
+type views struct {
+	filesystem afero.Fs
+	settings   map[string]interface{}
+	engines    map[string]goja.Callable
+	locals     *goja.Object
+
+	mu    sync.Mutex
+	cache map[string]*template.Template
+}
+
+func newViews(filesystem afero.Fs, settings map[string]interface{}) *views {
+	return &views{ //nolint:exhaustruct
+		filesystem: filesystem,
+		settings:   settings,
+		engines:    make(map[string]goja.Callable),
+		cache:      make(map[string]*template.Template),
+	}
+}
+
+func defaultSettings() map[string]interface{} {
+	return map[string]interface{}{
+		settingViews:     defaultViewsDir,
+		settingViewCache: true,
+	}
+}
+
+func normalizeExt(ext string) string {
+	if len(ext) == 0 || strings.HasPrefix(ext, ".") {
+		return ext
+	}
+
+	return "." + ext
+}
+
+// lookup returns the file path and extension of the named view.
+// The default extension comes from the "view engine" setting.
+func (v *views) lookup(name string) (string, string) {
+	ext := path.Ext(name)
+
+	if len(ext) == 0 {
+		ext = builtinViewEngine
+
+		if engine, ok := v.settings[settingViewEngine].(string); ok && len(engine) != 0 {
+			ext = normalizeExt(engine)
+		}
+
+		name += ext
+	}
+
+	dir, _ := v.settings[settingViews].(string)
+
+	return path.Join(filepath.ToSlash(dir), name), ext
+}
+
+func (v *views) cached() bool {
+	enabled, ok := v.settings[settingViewCache].(bool)
+
+	return !ok || enabled
+}
+
+// render renders the named view with given locals.
+func (v *views) render(runtime *goja.Runtime, name string, locals map[string]interface{}) (string, error) {
+	file, ext := v.lookup(name)
+
+	if engine, ok := v.engines[ext]; ok {
+		return v.renderWith(runtime, engine, file, locals)
+	}
+
+	if ext != builtinViewEngine {
+		return "", fmt.Errorf("%w for extension: %s", errNoViewEngine, ext)
+	}
+
+	tmpl, err := v.template(file)
+	if err != nil {
+		return "", err
+	}
+
+	var buff bytes.Buffer
+
+	if err := tmpl.Execute(&buff, locals); err != nil {
+		return "", err
+	}
+
+	return buff.String(), nil
+}
+
+// renderWith calls the engine with the template source, the locals (extended with filename) and a callback.
+// The engine should call the callback synchronously with an error or the rendered string, or return the rendered string.
+func (v *views) renderWith(runtime *goja.Runtime, engine goja.Callable, file string, locals map[string]interface{}) (string, error) {
+	source, err := afero.ReadFile(v.filesystem, file)
+	if err != nil {
+		return "", err
+	}
+
+	options := make(map[string]interface{}, len(locals)+1)
+
+	for key, value := range locals {
+		options[key] = value
+	}
+
+	options[viewEngineFilename] = file
+
+	var (
+		result    string
+		resultErr error
+		called    bool
+	)
+
+	callback := func(err goja.Value, html goja.Value) {
+		called = true
+
+		if isSet(err) {
+			resultErr = fmt.Errorf("%s", err.String()) //nolint:goerr113
+		} else if isSet(html) {
+			result = html.String()
+		}
+	}
+
+	ret, err := engine(goja.Undefined(), runtime.ToValue(string(source)), runtime.ToValue(options), runtime.ToValue(callback))
+	if err != nil {
+		return "", err
+	}
+
+	if !called && isSet(ret) {
+		result = ret.String()
+	}
+
+	return result, resultErr
+}
+
+// template returns the compiled template for file.
+// Other templates from the views directory are available for {{template}} calls by their relative path.
+func (v *views) template(file string) (*template.Template, error) {
+	cache := v.cached()
+
+	if cache {
+		v.mu.Lock()
+		tmpl, found := v.cache[file]
+		v.mu.Unlock()
+
+		if found {
+			return tmpl, nil
+		}
+	}
+
+	tmpl, err := v.compile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache {
+		v.mu.Lock()
+		v.cache[file] = tmpl
+		v.mu.Unlock()
+	}
+
+	return tmpl, nil
+}
+
+// compile parses the other templates of the views directory first, so the definitions of file take precedence.
+func (v *views) compile(file string) (*template.Template, error) {
+	source, err := afero.ReadFile(v.filesystem, file)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, _ := v.settings[settingViews].(string)
+	dir = path.Clean(filepath.ToSlash(dir))
+
+	tmpl := template.New(file)
+
+	err = afero.Walk(v.filesystem, dir, func(name string, info fs.FileInfo, err error) error {
+		name = filepath.ToSlash(name)
+
+		if err != nil || info.IsDir() || path.Ext(name) != builtinViewEngine || name == file {
+			return err
+		}
+
+		partial, err := afero.ReadFile(v.filesystem, name)
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, dir), "/")
+
+		_, err = tmpl.New(rel).Parse(string(partial))
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl.Parse(string(source))
+}
+
+// render renders a view and sends the rendered HTML string.
+// When a callback is given, it is called with an error or the rendered string instead of sending.
+func (resp *response) render(name string, locals goja.Value, callback goja.Value) *goja.Object {
+	if _, isFunc := goja.AssertFunction(locals); isFunc && !isSet(callback) {
+		locals, callback = goja.Undefined(), locals
+	}
+
+	if resp.views == nil {
+		throwf(resp.runtime, "views not available")
+	}
+
+	html, err := resp.views.render(resp.runtime, name, resp.renderLocals(locals))
+
+	if fn, isFunc := goja.AssertFunction(callback); isFunc {
+		errValue := goja.Null()
+		if err != nil {
+			errValue = resp.runtime.NewGoError(err)
+		}
+
+		_, err := fn(goja.Undefined(), errValue, resp.runtime.ToValue(html))
+
+		must(resp.runtime, err)
+
+		return resp.this
+	}
+
+	must(resp.runtime, err)
+
+	return resp.html([]byte(html))
+}
+
+// renderLocals merges app.locals, res.locals and the given locals, later ones take precedence.
+func (resp *response) renderLocals(locals goja.Value) map[string]interface{} {
+	merged := make(map[string]interface{})
+
+	sources := []goja.Value{resp.views.locals, resp.this.Get("locals"), locals}
+
+	for _, source := range sources {
+		obj, ok := source.(*goja.Object)
+		if !ok || obj == nil {
+			continue
+		}
+
+		for _, key := range obj.Keys() {
+			merged[key] = obj.Get(key).Export()
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestViews(t *testing.T) *views {
+	t.Helper()
+
+	filesystem := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"views/layout.html":        `<html>{{block "content" .}}{{end}}</html>`,
+		"views/index.html":         `{{define "content"}}Hello, {{.name}}!{{end}}{{template "layout.html" .}}`,
+		"views/other.html":         `{{define "content"}}Other{{end}}{{template "layout.html" .}}`,
+		"views/partials/item.html": `<li>{{.}}</li>`,
+		"views/list.html":          `<ul>{{range .items}}{{template "partials/item.html" .}}{{end}}</ul>`,
+		"views/hello.txt":          `Hello, NAME!`,
+	}
+
+	for name, content := range files {
+		assert.NoError(t, afero.WriteFile(filesystem, name, []byte(content), 0o644))
+	}
+
+	return newViews(filesystem, defaultSettings())
+}
+
+func Test_views_render(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	views := newTestViews(t)
+
+	html, err := views.render(runtime, "index", map[string]interface{}{"name": "<b>World</b>"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<html>Hello, &lt;b&gt;World&lt;/b&gt;!</html>", html)
+
+	html, err = views.render(runtime, "list.html", map[string]interface{}{"items": []string{"foo", "bar"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<ul><li>foo</li><li>bar</li></ul>", html)
+
+	_, err = views.render(runtime, "missing", nil)
+
+	assert.Error(t, err)
+
+	_, err = views.render(runtime, "hello.txt", nil)
+
+	assert.ErrorIs(t, err, errNoViewEngine)
+}
+
+func Test_views_cache(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	views := newTestViews(t)
+
+	html, err := views.render(runtime, "other", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<html>Other</html>", html)
+
+	assert.NoError(t, afero.WriteFile(views.filesystem, "views/other.html", []byte("Changed"), 0o644))
+
+	html, err = views.render(runtime, "other", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<html>Other</html>", html)
+
+	views.settings[settingViewCache] = false
+
+	html, err = views.render(runtime, "other", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Changed", html)
+}
+
+func Test_views_engine(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	views := newTestViews(t)
+
+	value, err := runtime.RunString(`
+	(source, options, callback) => callback(null, source.replace('NAME', options.name) + ' ' + options.filename)
+	`)
+
+	assert.NoError(t, err)
+
+	engine, ok := goja.AssertFunction(value)
+
+	assert.True(t, ok)
+
+	views.engines[".txt"] = engine
+	views.settings[settingViewEngine] = "txt"
+
+	html, err := views.render(runtime, "hello", map[string]interface{}{"name": "World"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, World! views/hello.txt", html)
+
+	value, err = runtime.RunString(`(source, options, callback) => callback(new Error("failed"))`)
+
+	assert.NoError(t, err)
+
+	views.engines[".txt"], _ = goja.AssertFunction(value)
+
+	_, err = views.render(runtime, "hello", nil)
+
+	assert.Error(t, err)
+}
+
+func Test_response_render(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	res.views = newTestViews(t)
+	res.views.locals = runtime.NewObject()
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	assert.NoError(t, res.views.locals.Set("name", "app"))
+
+	var rendered string
+
+	callMethod(t, obj, "render", value("index"), value(func(err goja.Value, html string) {
+		assert.True(t, goja.IsNull(err))
+
+		rendered = html
+	}))
+
+	assert.Equal(t, "<html>Hello, app!</html>", rendered)
+
+	assert.NoError(t, obj.Get("locals").(*goja.Object).Set("name", "res"))
+
+	callMethod(t, obj, "render", value("index"), value(func(err goja.Value, html string) { rendered = html }))
+
+	assert.Equal(t, "<html>Hello, res!</html>", rendered)
+
+	callMethod(t, obj, "render", value("index"), value(map[string]interface{}{"name": "World"}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<html>Hello, World!</html>", rec.Body.String())
+}
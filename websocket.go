@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+)
+
+const wsControlTimeout = 5 * time.Second
+
+var wsEvents = map[string]struct{}{"message": {}, "ping": {}, "pong": {}, "close": {}, "error": {}}
+
+// upgrader accepts cross-origin clients too, browser pages of any origin may connect to a mock server.
+var upgrader = websocket.Upgrader{ //nolint:exhaustruct
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+func wrapWebSocket(runtime *goja.Runtime, socket *webSocket) *goja.Object {
+	this := runtime.NewObject()
+
+	socket.this = this
+
+	mustSet(runtime, this, "send", socket.send)
+	mustSet(runtime, this, "ping", socket.ping)
+	mustSet(runtime, this, "pong", socket.pong)
+	mustSet(runtime, this, "close", socket.close)
+	mustSet(runtime, this, "on", socket.on)
+
+	return this
+}
+
+// webSocket wraps a WebSocket connection. Event handlers are called via runner.
+type webSocket struct {
+	conn    *websocket.Conn
+	runtime *goja.Runtime
+	runner  RunnerFunc
+	this    *goja.Object
+
+	handlers map[string][]goja.Callable
+	mu       sync.Mutex // guards data message writes
+}
+
+func newWebSocket(runtime *goja.Runtime, conn *websocket.Conn, runner RunnerFunc) *webSocket {
+	return &webSocket{ //nolint:exhaustruct
+		conn:     conn,
+		runtime:  runtime,
+		runner:   runner,
+		handlers: make(map[string][]goja.Callable),
+	}
+}
+
+// send sends a text message for string data and a binary message otherwise.
+func (socket *webSocket) send(data goja.Value) *goja.Object {
+	msgType := websocket.BinaryMessage
+
+	if _, ok := data.Export().(string); ok {
+		msgType = websocket.TextMessage
+	}
+
+	payload := toBytes(socket.runtime, data)
+
+	socket.mu.Lock()
+	defer socket.mu.Unlock()
+
+	must(socket.runtime, socket.conn.WriteMessage(msgType, payload))
+
+	return socket.this
+}
+
+func (socket *webSocket) ping(data goja.Value) *goja.Object {
+	return socket.control(websocket.PingMessage, data)
+}
+
+func (socket *webSocket) pong(data goja.Value) *goja.Object {
+	return socket.control(websocket.PongMessage, data)
+}
+
+func (socket *webSocket) control(msgType int, data goja.Value) *goja.Object {
+	var payload []byte
+
+	if isSet(data) {
+		payload = toBytes(socket.runtime, data)
+	}
+
+	must(socket.runtime, socket.conn.WriteControl(msgType, payload, time.Now().Add(wsControlTimeout)))
+
+	return socket.this
+}
+
+// close starts the closing handshake with the given code (default 1000) and reason.
+func (socket *webSocket) close(code goja.Value, reason string) *goja.Object {
+	closeCode := websocket.CloseNormalClosure
+
+	if isSet(code) {
+		closeCode = int(code.ToInteger())
+	}
+
+	msg := websocket.FormatCloseMessage(closeCode, reason)
+
+	err := socket.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsControlTimeout))
+	if !errors.Is(err, websocket.ErrCloseSent) {
+		must(socket.runtime, err)
+	}
+
+	return socket.this
+}
+
+// on registers event handler. Supported events are message, ping, pong, close and error.
+func (socket *webSocket) on(name string, handler goja.Callable) *goja.Object {
+	if _, ok := wsEvents[name]; !ok {
+		throwf(socket.runtime, "unsupported event: %s", name)
+	}
+
+	socket.handlers[name] = append(socket.handlers[name], handler)
+
+	return socket.this
+}
+
+// emit calls event handlers via runner. Byte slice arguments are passed as ArrayBuffer, errors as Error objects.
+func (socket *webSocket) emit(name string, args ...interface{}) {
+	socket.runner(func() error {
+		handlers := socket.handlers[name]
+		if len(handlers) == 0 {
+			return nil
+		}
+
+		values := make([]goja.Value, len(args))
+
+		for idx, arg := range args {
+			switch val := arg.(type) {
+			case []byte:
+				values[idx] = socket.runtime.ToValue(socket.runtime.NewArrayBuffer(val))
+			case error:
+				values[idx] = socket.runtime.NewGoError(val)
+			default:
+				values[idx] = socket.runtime.ToValue(val)
+			}
+		}
+
+		for _, handler := range handlers {
+			if _, err := handler(socket.this, values...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// serve reads incoming messages until the connection is closed.
+func (socket *webSocket) serve() {
+	defer socket.conn.Close()
+
+	socket.conn.SetPingHandler(func(data string) error {
+		err := socket.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(wsControlTimeout))
+		if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+			return err
+		}
+
+		socket.emit("ping", []byte(data))
+
+		return nil
+	})
+
+	socket.conn.SetPongHandler(func(data string) error {
+		socket.emit("pong", []byte(data))
+
+		return nil
+	})
+
+	for {
+		msgType, data, err := socket.conn.ReadMessage()
+		if err != nil {
+			code, reason := websocket.CloseAbnormalClosure, ""
+
+			var closeErr *websocket.CloseError
+
+			if errors.As(err, &closeErr) {
+				code, reason = closeErr.Code, closeErr.Text
+			} else {
+				socket.emit("error", err)
+			}
+
+			socket.emit("close", code, reason)
+
+			return
+		}
+
+		if msgType == websocket.TextMessage {
+			socket.emit("message", string(data), false)
+		} else {
+			socket.emit("message", data, true)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+const wsTestHandler = `
+(socket, req) => {
+	socket.on('message', (data, binary) => socket.send(binary ? data : 'echo ' + data))
+	socket.on('ping', data => record('ping ' + String.fromCharCode(...new Uint8Array(data))))
+	socket.on('close', (code, reason) => record('close ' + code + ' ' + reason))
+	socket.ping('hello')
+}
+`
+
+func newWebSocketServer(t *testing.T, runtime *goja.Runtime, mws ...middleware) (*httptest.Server, chan string) {
+	t.Helper()
+
+	router := newRouter(syncRunner(), nil)
+	events := make(chan string, 8)
+
+	assert.NoError(t, runtime.Set("record", func(event string) { events <- event }))
+
+	value, err := runtime.RunString(wsTestHandler)
+
+	assert.NoError(t, err)
+
+	handler, ok := goja.AssertFunction(value)
+
+	assert.True(t, ok)
+
+	router.use(mws...)
+	router.handleWebSocket(runtime, "/ws", handler)
+
+	return httptest.NewServer(router), events
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+func Test_router_handleWebSocket(t *testing.T) {
+	t.Parallel()
+
+	srv, events := newWebSocketServer(t, goja.New())
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(srv), nil)
+
+	assert.NoError(t, err)
+
+	defer conn.Close()
+
+	pings := make(chan string, 1)
+
+	conn.SetPingHandler(func(data string) error {
+		pings <- data
+
+		return nil
+	})
+
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+
+	msgType, data, err := conn.ReadMessage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, msgType)
+	assert.Equal(t, "echo hello", string(data))
+	assert.Equal(t, "hello", <-pings)
+
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3}))
+
+	msgType, data, err = conn.ReadMessage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, msgType)
+	assert.Equal(t, []byte{1, 2, 3}, data)
+
+	assert.NoError(t, conn.WriteControl(websocket.PingMessage, []byte("foo"), time.Now().Add(time.Second)))
+	assert.Equal(t, "ping foo", <-events)
+
+	msg := websocket.FormatCloseMessage(4000, "bye")
+
+	assert.NoError(t, conn.WriteMessage(websocket.CloseMessage, msg))
+
+	_, _, err = conn.ReadMessage()
+
+	assert.True(t, websocket.IsCloseError(err, 4000))
+	assert.Equal(t, "close 4000 bye", <-events)
+}
+
+func Test_router_handleWebSocket_rejected(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	var deny middleware = func(req *goja.Object, res *goja.Object, next goja.Callable) {
+		callMethod(t, res, "status", runtime.ToValue(http.StatusUnauthorized))
+		callMethod(t, res, "text", runtime.ToValue("denied"))
+	}
+
+	srv, _ := newWebSocketServer(t, runtime, deny)
+	defer srv.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(srv), nil)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp.Body.Close()
+}
+
+func Test_router_handleWebSocket_notUpgrade(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newWebSocketServer(t, goja.New())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws") //nolint:noctx
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp.Body.Close()
+}
+
+func Test_router_handleWebSocket_crossOrigin(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newWebSocketServer(t, goja.New())
+	defer srv.Close()
+
+	header := http.Header{"Origin": []string{"https://example.com"}}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(srv), header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	resp.Body.Close()
+	conn.Close()
+}
+
+func Test_router_handleWebSocket_shared(t *testing.T) {
+	t.Parallel()
+
+	for _, webSocketFirst := range []bool{true, false} {
+		runtime := goja.New()
+		router := newRouter(syncRunner(), nil)
+
+		assert.NoError(t, runtime.Set("record", func(event string) {}))
+
+		value, err := runtime.RunString(wsTestHandler)
+
+		assert.NoError(t, err)
+
+		handler, ok := goja.AssertFunction(value)
+
+		assert.True(t, ok)
+
+		if webSocketFirst {
+			router.handleWebSocket(runtime, "/ws", handler)
+			router.handleMethod(runtime, http.MethodGet, "/ws", newEcho(t, runtime))
+		} else {
+			router.handleMethod(runtime, http.MethodGet, "/ws", newEcho(t, runtime))
+			router.handleWebSocket(runtime, "/ws", handler)
+		}
+
+		srv := httptest.NewServer(router)
+
+		resp, err := http.Get(srv.URL + "/ws?message=Hello") //nolint:noctx
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello", string(body))
+
+		resp.Body.Close()
+
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL(srv), nil)
+
+		assert.NoError(t, err)
+
+		resp.Body.Close()
+
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+
+		_, data, err := conn.ReadMessage()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "echo hello", string(data))
+
+		conn.Close()
+		srv.Close()
+	}
+}
+
+func Test_router_handleWebSocket_duplicate(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	router := newRouter(syncRunner(), nil)
+
+	router.handleWebSocket(runtime, "/ws", nil)
+
+	assert.Panics(t, func() { router.handleWebSocket(runtime, "/ws", nil) })
+
+	router.handleMethod(runtime, http.MethodGet, "/ws", newEcho(t, runtime))
+
+	assert.Panics(t, func() { router.handleMethod(runtime, http.MethodGet, "/ws", newEcho(t, runtime)) })
+}
@@ -0,0 +1,315 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"golang.org/x/net/html/charset"
+)
+
+// Object to XML mapping:
+//
+//   - an object with a single (non-array) property is the root element, otherwise the properties are wrapped in a root element
+//   - properties with @ prefix are attributes, the #text property is the text content
+//   - array values are repeated elements with the name of the property, items of a top-level array are item elements
+//   - other values are text content, null and undefined are empty elements
+//
+// Parsing uses the same mapping: elements without attributes and child elements are strings,
+// namespace prefixes of element names are dropped.
+const (
+	xmlAttrPrefix = "@"
+	xmlTextKey    = "#text"
+	xmlRootName   = "root"
+	xmlItemName   = "item"
+	xmlHeader     = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+)
+
+var (
+	errXMLName  = errors.New("invalid XML element or attribute name")
+	errXMLEmpty = errors.New("empty XML document")
+)
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+// xml sends an XML response. Strings are sent as is, other values are serialized.
+func (resp *response) xml(data goja.Value) *goja.Object {
+	resp.Header().Set("Content-Type", "application/xml; charset=utf-8")
+
+	if str, isString := data.Export().(string); isString {
+		resp.write([]byte(str))
+
+		return resp.this
+	}
+
+	bin, err := marshalXML(data)
+
+	must(resp.runtime, err)
+
+	resp.write(bin)
+
+	return resp.this
+}
+
+func marshalXML(value goja.Value) ([]byte, error) {
+	var buff bytes.Buffer
+
+	buff.WriteString(xmlHeader)
+
+	obj, isObject := value.(*goja.Object)
+
+	var err error
+
+	switch {
+	case isObject && isArray(obj):
+		buff.WriteString("<" + xmlRootName + ">")
+
+		err = writeXMLElement(&buff, xmlItemName, value)
+
+		buff.WriteString("</" + xmlRootName + ">")
+	case isObject && len(obj.Keys()) == 1 && !isArrayValue(obj.Get(obj.Keys()[0])):
+		name := obj.Keys()[0]
+
+		err = writeXMLElement(&buff, name, obj.Get(name))
+	default:
+		err = writeXMLElement(&buff, xmlRootName, value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+func isArrayValue(value goja.Value) bool {
+	obj, isObject := value.(*goja.Object)
+
+	return isObject && isArray(obj)
+}
+
+// validXMLName returns false for names which would break the markup, like names containing whitespace or quotes.
+func validXMLName(name string) bool {
+	return len(name) != 0 && !strings.ContainsAny(name, " \t\r\n<>&\"'/=")
+}
+
+func isArray(obj *goja.Object) bool {
+	return obj.ClassName() == "Array"
+}
+
+func writeXMLElement(buff *bytes.Buffer, name string, value goja.Value) error {
+	if !validXMLName(name) {
+		return errXMLName
+	}
+
+	if obj, isObject := value.(*goja.Object); isObject && isArray(obj) {
+		for _, key := range obj.Keys() {
+			if err := writeXMLElement(buff, name, obj.Get(key)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	buff.WriteString("<" + name)
+
+	obj, isObject := value.(*goja.Object)
+	if !isObject || isDate(obj) {
+		if !isSet(value) {
+			buff.WriteString("/>")
+
+			return nil
+		}
+
+		buff.WriteString(">" + xmlEscaper.Replace(value.String()) + "</" + name + ">")
+
+		return nil
+	}
+
+	for _, key := range obj.Keys() {
+		if !strings.HasPrefix(key, xmlAttrPrefix) {
+			continue
+		}
+
+		attr := strings.TrimPrefix(key, xmlAttrPrefix)
+		if !validXMLName(attr) {
+			return errXMLName
+		}
+
+		buff.WriteString(" " + attr + `="` + xmlEscaper.Replace(obj.Get(key).String()) + `"`)
+	}
+
+	buff.WriteString(">")
+
+	for _, key := range obj.Keys() {
+		switch {
+		case strings.HasPrefix(key, xmlAttrPrefix):
+		case key == xmlTextKey:
+			buff.WriteString(xmlEscaper.Replace(obj.Get(key).String()))
+		default:
+			if err := writeXMLElement(buff, key, obj.Get(key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	buff.WriteString("</" + name + ">")
+
+	return nil
+}
+
+func isDate(obj *goja.Object) bool {
+	return obj.ClassName() == "Date"
+}
+
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// unmarshalXML parses XML document into generic value using the mapping described above.
+func unmarshalXML(data []byte) (map[string]interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel // documents declaring other encodings than UTF-8 are transcoded
+
+	var (
+		root  *xmlNode
+		stack []*xmlNode
+	)
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: tok.Name.Local, attrs: tok.Attr} //nolint:exhaustruct
+
+			if len(stack) == 0 {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) != 0 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errXMLEmpty
+	}
+
+	return map[string]interface{}{root.name: root.value()}, nil
+}
+
+func (node *xmlNode) value() interface{} {
+	text := strings.TrimSpace(node.text.String())
+
+	if len(node.attrs) == 0 && len(node.children) == 0 {
+		return text
+	}
+
+	out := make(map[string]interface{})
+
+	for _, attr := range node.attrs {
+		name := attr.Name.Local
+
+		if attr.Name.Space == "xmlns" {
+			name = "xmlns:" + name
+		}
+
+		out[xmlAttrPrefix+name] = attr.Value
+	}
+
+	for _, child := range node.children {
+		value := child.value()
+
+		switch current := out[child.name].(type) {
+		case nil:
+			out[child.name] = value
+		case []interface{}:
+			out[child.name] = append(current, value)
+		default:
+			out[child.name] = []interface{}{current, value}
+		}
+	}
+
+	if len(text) != 0 {
+		out[xmlTextKey] = text
+	}
+
+	return out
+}
+
+// isXMLType returns true for application/xml, text/xml and +xml media types.
+func isXMLType(contentType string) bool {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediatype == "application/xml" || mediatype == "text/xml" || strings.HasSuffix(mediatype, "+xml")
+}
+
+// prefersXML returns true if the Accept header field value prefers XML over JSON.
+func prefersXML(accept string) bool {
+	return acceptQuality(accept, "application/xml", "text/xml") > acceptQuality(accept, "application/json")
+}
+
+// acceptQuality returns the highest quality value of Accept header field for the given media types.
+// Only exact matches are considered, so wildcards do not change the default JSON response.
+func acceptQuality(accept string, mediatypes ...string) float64 {
+	var quality float64
+
+	for _, part := range strings.Split(accept, ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		for _, candidate := range mediatypes {
+			if mediatype != candidate {
+				continue
+			}
+
+			q := 1.0
+
+			if value, found := params["q"]; found {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+
+			if q > quality {
+				quality = q
+			}
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_marshalXML(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+
+	for script, expected := range map[string]string{
+		`({user: {"@id": 1, name: "Joe & Co", tags: ["a", "b"], empty: null}})`: `<user id="1"><name>Joe &amp; Co</name><tags>a</tags><tags>b</tags><empty/></user>`,
+		`({name: "Joe", age: 42})`:                      `<root><name>Joe</name><age>42</age></root>`,
+		`({price: {"@currency": "EUR", "#text": 9.5}})`: `<price currency="EUR">9.5</price>`,
+		`(["a", "b"])`:                                  `<root><item>a</item><item>b</item></root>`,
+		`({user: ["a", "b"]})`:                          `<root><user>a</user><user>b</user></root>`,
+		`("<text>")`:                                    `<root>&lt;text&gt;</root>`,
+	} {
+		value, err := runtime.RunString(script)
+
+		assert.NoError(t, err)
+
+		bin, err := marshalXML(value)
+
+		assert.NoError(t, err)
+		assert.Equal(t, xmlHeader+expected, string(bin), script)
+	}
+
+	for _, script := range []string{
+		`({"bad name": 1})`,
+		`({user: {"@x onload=\"alert(1)\" y": 1}})`,
+		`({user: {"@": 1}})`,
+	} {
+		value, err := runtime.RunString(script)
+
+		assert.NoError(t, err)
+
+		_, err = marshalXML(value)
+
+		assert.ErrorIs(t, err, errXMLName, script)
+	}
+}
+
+func Test_unmarshalXML(t *testing.T) {
+	t.Parallel()
+
+	doc := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetUser id="42">
+      <name>Joe</name>
+      <tag>a</tag>
+      <tag>b</tag>
+      <price currency="EUR">9.5</price>
+    </GetUser>
+  </soap:Body>
+</soap:Envelope>`
+
+	out, err := unmarshalXML([]byte(doc))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Envelope": map[string]interface{}{
+			"@xmlns:soap": "http://schemas.xmlsoap.org/soap/envelope/",
+			"Body": map[string]interface{}{
+				"GetUser": map[string]interface{}{
+					"@id":   "42",
+					"name":  "Joe",
+					"tag":   []interface{}{"a", "b"},
+					"price": map[string]interface{}{"@currency": "EUR", "#text": "9.5"},
+				},
+			},
+		},
+	}, out)
+
+	_, err = unmarshalXML([]byte(""))
+
+	assert.ErrorIs(t, err, errXMLEmpty)
+
+	_, err = unmarshalXML([]byte("<foo>"))
+
+	assert.Error(t, err)
+}
+
+func Test_unmarshalXML_charset(t *testing.T) {
+	t.Parallel()
+
+	out, err := unmarshalXML([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><name>Jos\xe9</name>"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "José"}, out)
+
+	_, err = unmarshalXML([]byte(`<?xml version="1.0" encoding="x-unknown"?><name>Joe</name>`))
+
+	assert.Error(t, err)
+}
+
+func Test_prefersXML(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, prefersXML("application/xml"))
+	assert.True(t, prefersXML("text/xml, application/json;q=0.5"))
+	assert.False(t, prefersXML(""))
+	assert.False(t, prefersXML("*/*"))
+	assert.False(t, prefersXML("application/json, application/xml"))
+	assert.False(t, prefersXML("application/xml;q=0.1, application/json"))
+
+	assert.True(t, isXMLType("application/soap+xml; charset=utf-8"))
+	assert.True(t, isXMLType("text/xml"))
+	assert.False(t, isXMLType("application/json"))
+}
+
+func Test_response_xml(t *testing.T) {
+	t.Parallel()
+
+	runtime := goja.New()
+	rec := httptest.NewRecorder()
+	res := newResponse(runtime, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	obj := wrapResponse(runtime, res)
+	value := runtime.ToValue
+
+	callMethod(t, obj, "xml", value("<ok/>"))
+
+	assert.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<ok/>", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/xml")
+
+	rec = httptest.NewRecorder()
+	res = newResponse(runtime, rec, req)
+	obj = wrapResponse(runtime, res)
+
+	callMethod(t, obj, "send", value(map[string]interface{}{"foo": "bar"}))
+
+	assert.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	assert.Equal(t, xmlHeader+"<foo>bar</foo>", rec.Body.String())
+}